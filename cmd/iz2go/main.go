@@ -3,6 +3,9 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"log"
 	"os"
 	"os/exec"
@@ -10,6 +13,7 @@ import (
 	"strings"
 	"text/template"
 
+	iz2go "github.com/LingHeChen/iz2go/pkg/core"
 	"github.com/spf13/cobra"
 )
 
@@ -23,17 +27,29 @@ import (
 	{{- end}}
 )
 
-func InitRoutes() {
+// InitRoutes 将所有路由注册到 iz2go.DefaultRegistry()，供 iz2go.Default() 使用
+func InitRoutes() error {
+	return RegisterRoutes(iz2go.DefaultRegistry())
+}
+
+// RegisterRoutes 将所有路由注册到指定的 Registry，每次调用都会创建新的处理器实例
+func RegisterRoutes(registry *iz2go.Registry) error {
 	{{- range $index, $value := .Routes}}
 	// Register {{.Path}}
 	{
 		api := &mod{{$index}}.{{.ApiName}}{}
-		handlerInfo := iz2go.BuildHandler(api)
+		handlerInfo, err := iz2go.TryBuildHandler(api)
+		if err != nil {
+			return err
+		}
 		if handlerInfo != nil {
-			iz2go.RegisterRoute("{{.Path}}", handlerInfo)
+			if err := registry.RegisterRoute("{{.Path}}", handlerInfo); err != nil {
+				return err
+			}
 		}
 	}
 	{{- end}}
+	return nil
 }
 `
 
@@ -41,6 +57,161 @@ type Route struct {
 	ImportPath string
 	Path       string
 	ApiName    string
+	// Method 处理器的请求方法，无法在生成时确定时为空
+	Method string
+	// Source 处理器所在的文件，相对于项目根目录
+	Source string
+}
+
+// 嵌入后决定请求方法的类型
+var methodTypes = map[string]string{
+	"Get":       "GET",
+	"Post":      "POST",
+	"Put":       "PUT",
+	"Delete":    "DELETE",
+	"Patch":     "PATCH",
+	"Options":   "OPTIONS",
+	"Head":      "HEAD",
+	"Trace":     "TRACE",
+	"Connect":   "CONNECT",
+	"WebSocket": "GET",
+}
+
+// iz2go 包的导入路径，只有这个包中的类型嵌入后才能确定请求方法
+const corePackagePath = "github.com/LingHeChen/iz2go/pkg/core"
+
+// parseRouteMethod 从处理器所在包的源文件中解析请求方法，与 iz2go.ParseMethod 的规则一致：
+// 嵌入 iz2go.Post 等类型或实现返回字符串字面量的 GetMethod，否则为 GET。
+// 无法确定时返回空字符串，例如 GetMethod 返回的不是字面量，或者嵌入了其他包或本包中的类型（这些类型可能提供 GetMethod）
+func parseRouteMethod(filePath string, handlerName string) (string, error) {
+	dir := filepath.Dir(filePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var structType *ast.StructType
+	var coreName string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return "", err
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok || typeSpec.Name.Name != handlerName {
+						continue
+					}
+					if t, ok := typeSpec.Type.(*ast.StructType); ok {
+						structType, coreName = t, importName(file, corePackagePath)
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Name.Name == "GetMethod" && decl.Recv != nil && len(decl.Recv.List) > 0 &&
+					embeddedTypeName(decl.Recv.List[0].Type) == handlerName {
+					return literalMethod(decl.Body), nil
+				}
+			}
+		}
+	}
+	if structType == nil {
+		return "", nil
+	}
+	method := "GET"
+	for _, field := range structType.Fields.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		typeName := field.Type
+		if star, ok := typeName.(*ast.StarExpr); ok {
+			typeName = star.X
+		}
+		selector, ok := typeName.(*ast.SelectorExpr)
+		if !ok || coreName == "" {
+			return "", nil
+		}
+		if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != coreName {
+			return "", nil
+		}
+		// iz2go 中的其他类型（例如 SparseFields）不影响请求方法
+		if m, ok := methodTypes[selector.Sel.Name]; ok {
+			method = m
+		}
+	}
+	return method, nil
+}
+
+// importName 返回文件中导入 path 时使用的包名，没有导入时返回空字符串
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		if strings.Trim(spec.Path.Value, "\"`") != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return "iz2go"
+	}
+	return ""
+}
+
+// embeddedTypeName 返回 T、*T、pkg.T、*pkg.T 中的 T
+func embeddedTypeName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
+// literalMethod 返回只包含 return "METHOD" 的函数体中的请求方法
+func literalMethod(body *ast.BlockStmt) string {
+	if body == nil || len(body.List) != 1 {
+		return ""
+	}
+	ret, ok := body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	return strings.ToUpper(strings.Trim(lit.Value, "\"`"))
+}
+
+// checkConflicts 检查同一请求方法下重复或在 gin 中冲突的路由，报告两个处理器所在的文件
+func checkConflicts(routes []Route) error {
+	var conflicts []string
+	for i, route := range routes {
+		if route.Method == "" {
+			continue
+		}
+		for _, other := range routes[:i] {
+			if other.Method != route.Method {
+				continue
+			}
+			if reason := iz2go.ConflictReason(route.Path, other.Path); reason != "" {
+				conflicts = append(conflicts, fmt.Sprintf("%s %s (%s) 与 %s %s (%s): %s",
+					route.Method, route.Path, route.Source, other.Method, other.Path, other.Source, reason))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("路由冲突:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return nil
 }
 
 // 从 go.mod 解析模块路径
@@ -83,10 +254,19 @@ func getRoutes(rootPath, routeModulePath, modulePath string) []Route {
 
 		path := strings.Replace(importPath, "/routes", "", 1)
 
+		// 解析失败时不检查冲突，语法错误由编译器报告
+		method, _ := parseRouteMethod(filePath, handlerName)
+		source, err := filepath.Rel(rootPath, filePath)
+		if err != nil {
+			source = filePath
+		}
+
 		routes = append(routes, Route{
 			ImportPath: modulePath + importPath,
 			Path:       path + "/" + apiName,
 			ApiName:    handlerName,
+			Method:     method,
+			Source:     source,
 		})
 
 		return nil
@@ -121,12 +301,16 @@ func runCmdGen(c *cobra.Command, args []string) {
 	if len(args) > 0 {
 		routeModulePath = args[0]
 	}
+	routes := getRoutes(rootPath, routeModulePath, modulePath)
+	if err := checkConflicts(routes); err != nil {
+		log.Fatal(err)
+	}
 	templates := template.Must(template.New("code").Parse(codeTemplate))
 	var buf bytes.Buffer
 	templates.Execute(&buf, struct {
 		Routes []Route
 	}{
-		Routes: getRoutes(rootPath, routeModulePath, modulePath),
+		Routes: routes,
 	})
 	if err := os.MkdirAll(rootPath+"/api_gen", 0755); err != nil {
 		log.Fatal(err)
@@ -155,6 +339,41 @@ func runCmdRun(c *cobra.Command, args []string) {
 	startServer(filePath)
 }
 
+var (
+	errorsFormat string
+	errorsOutput string
+)
+
+func runCmdErrors(c *cobra.Command, args []string) {
+	catalog := iz2go.NewCatalog("")
+	if err := catalog.LoadFile(args[0]); err != nil {
+		log.Fatal("加载错误目录失败:", err)
+	}
+
+	out := os.Stdout
+	if errorsOutput != "" {
+		f, err := os.Create(errorsOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	switch strings.ToLower(errorsFormat) {
+	case "json":
+		err = catalog.WriteJSON(out)
+	case "markdown", "md":
+		err = catalog.WriteMarkdown(out)
+	default:
+		log.Fatalf("不支持的格式: %s", errorsFormat)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "iz2go",
 	Short: "iz2go is a tool for generating API routes",
@@ -172,6 +391,13 @@ var cmdRun = &cobra.Command{
 	Run:   runCmdRun,
 }
 
+var cmdErrors = &cobra.Command{
+	Use:   "errors <catalog.json|catalog.csv>",
+	Short: "errors exports the error catalog as JSON or Markdown",
+	Args:  cobra.ExactArgs(1),
+	Run:   runCmdErrors,
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -181,6 +407,10 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(cmdGen)
 	rootCmd.AddCommand(cmdRun)
+	rootCmd.AddCommand(cmdErrors)
+
+	cmdErrors.Flags().StringVarP(&errorsFormat, "format", "f", "markdown", "output format: json or markdown")
+	cmdErrors.Flags().StringVarP(&errorsOutput, "output", "o", "", "output file, defaults to stdout")
 }
 
 func main() {
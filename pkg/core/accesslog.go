@@ -0,0 +1,108 @@
+package iz2go
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader 接收和返回请求 ID 的请求头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey 请求 ID 在请求上下文中的键，处理器可以通过 `from:"ctx" mapping:"request_id"` 的字段获取
+	RequestIDKey = "request_id"
+)
+
+// 客户端传入的请求 ID 超过该长度或包含不可打印字符时重新生成
+const maxRequestIDLength = 128
+
+// AccessLogConfig 访问日志的配置
+type AccessLogConfig struct {
+	// Logger 默认为 slog.Default()
+	Logger *slog.Logger
+}
+
+// UseAccessLog 使用 slog 为所有路由记录访问日志，并为每个请求分配请求 ID：
+// 优先使用请求头 X-Request-ID，没有时生成一个，写入请求上下文和响应头。可以在 Build 之后调用。
+// Default() 仍然保留 gin 的文本日志，只需要 slog 日志时使用 NewBuilder().AccessLog(...)
+func (e *Engine) UseAccessLog(config AccessLogConfig) {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	e.accessLogger = config.Logger
+}
+
+func (e *Engine) getAccessLogger() *slog.Logger {
+	if e == nil {
+		return nil
+	}
+	return e.accessLogger
+}
+
+// RequestIDFromContext 获取当前请求的请求 ID，没有启用访问日志时返回空字符串
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// logRoute 分配请求 ID 并在请求结束后记录访问日志，没有启用访问日志时直接调用 next
+func logRoute(c *gin.Context, route *HandlerInfo, next gin.HandlerFunc) {
+	logger := engineFromContext(c).getAccessLogger()
+	if logger == nil {
+		next(c)
+		return
+	}
+	requestID := c.GetHeader(RequestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = newRequestID()
+	}
+	c.Set(RequestIDKey, requestID)
+	c.Header(RequestIDHeader, requestID)
+	start := time.Now()
+
+	next(c)
+
+	status := c.Writer.Status()
+	attrs := []slog.Attr{
+		slog.String("request_id", requestID),
+		slog.String("method", c.Request.Method),
+		slog.String("route", route.Path),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("handler", route.HandlerName),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", c.ClientIP()),
+	}
+	if err := ErrorFromContext(c); err != nil {
+		attrs = append(attrs, slog.Int("error_code", err.GetCode()))
+	}
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+	logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,325 @@
+package iz2go
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Redacted 审计日志中敏感字段的值
+const Redacted = "[REDACTED]"
+
+// 请求上下文中保存审计数据的键
+const auditContextKey = "iz2go.audit"
+
+// AuditRecord 一条审计记录，Request 和 Response 中标记了 sensitive:"true" 的字段已被替换为 Redacted
+type AuditRecord struct {
+	Time      time.Time     `json:"time"`
+	RequestID string        `json:"request_id,omitempty"`
+	Method    string        `json:"method"`
+	Route     string        `json:"route"`
+	Path      string        `json:"path"`
+	Handler   string        `json:"handler"`
+	Status    int           `json:"status"`
+	Duration  time.Duration `json:"duration"`
+	ErrorCode int           `json:"error_code,omitempty"`
+	Request   interface{}   `json:"request,omitempty"`
+	Response  interface{}   `json:"response,omitempty"`
+}
+
+// AuditSink 审计记录的输出位置
+type AuditSink interface {
+	WriteAudit(ctx context.Context, record AuditRecord) error
+}
+
+// AuditSinkFunc 将函数作为 AuditSink
+type AuditSinkFunc func(ctx context.Context, record AuditRecord) error
+
+func (f AuditSinkFunc) WriteAudit(ctx context.Context, record AuditRecord) error {
+	return f(ctx, record)
+}
+
+// SlogAuditSink 将审计记录写入 slog
+type SlogAuditSink struct {
+	Logger *slog.Logger
+}
+
+func (s SlogAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := []slog.Attr{
+		slog.String("request_id", record.RequestID),
+		slog.String("method", record.Method),
+		slog.String("route", record.Route),
+		slog.String("path", record.Path),
+		slog.String("handler", record.Handler),
+		slog.Int("status", record.Status),
+		slog.Duration("duration", record.Duration),
+		slog.Any("request", record.Request),
+		slog.Any("response", record.Response),
+	}
+	if record.ErrorCode != 0 {
+		attrs = append(attrs, slog.Int("error_code", record.ErrorCode))
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "audit", attrs...)
+	return nil
+}
+
+// FileAuditSink 以 JSON Lines 的格式将审计记录追加到本地文件
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+func (s *FileAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// Shutdown 关闭文件，可以通过 RegisterShutdownHook 在服务停止时调用
+func (s *FileAuditSink) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// AuditConfig 审计日志的配置
+type AuditConfig struct {
+	Sink AuditSink
+	// Methods 需要审计的请求方法，默认为 POST、PUT、PATCH、DELETE
+	Methods []string
+}
+
+type auditCapture struct {
+	request  interface{}
+	response interface{}
+}
+
+// UseAudit 为指定方法的路由记录绑定后的请求参数和 Execute 的返回值，写入失败时记录到 slog 不影响响应。可以在 Build 之后调用
+func (e *Engine) UseAudit(config AuditConfig) {
+	if config.Sink == nil {
+		config.Sink = SlogAuditSink{}
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	e.audit = &config
+}
+
+func (e *Engine) getAudit() *AuditConfig {
+	if e == nil {
+		return nil
+	}
+	return e.audit
+}
+
+// auditRoute 在请求结束后写入审计记录，没有启用审计或方法不需要审计时直接调用 next
+func auditRoute(c *gin.Context, route *HandlerInfo, next gin.HandlerFunc) {
+	config := engineFromContext(c).getAudit()
+	if config == nil || !slices.Contains(config.Methods, c.Request.Method) {
+		next(c)
+		return
+	}
+	capture := &auditCapture{}
+	c.Set(auditContextKey, capture)
+	start := time.Now()
+
+	next(c)
+
+	record := AuditRecord{
+		Time:      start,
+		RequestID: RequestIDFromContext(c),
+		Method:    c.Request.Method,
+		Route:     route.Path,
+		Path:      c.Request.URL.Path,
+		Handler:   route.HandlerName,
+		Status:    c.Writer.Status(),
+		Duration:  time.Since(start),
+		Request:   Redact(capture.request),
+		Response:  Redact(capture.response),
+	}
+	if err := ErrorFromContext(c); err != nil {
+		record.ErrorCode = err.GetCode()
+	}
+	if err := config.Sink.WriteAudit(c.Request.Context(), record); err != nil {
+		slog.ErrorContext(c.Request.Context(), "[iz2go] write audit record failed", "error", err)
+	}
+}
+
+func getAuditCapture(c *gin.Context) *auditCapture {
+	if value, ok := c.Get(auditContextKey); ok {
+		return value.(*auditCapture)
+	}
+	return nil
+}
+
+// IsSensitive 字段标记了 sensitive:"true" 时在审计日志中隐藏，文档中标记为 password 且只写
+func IsSensitive(field reflect.StructField) bool {
+	return field.Tag.Get("sensitive") == "true"
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 防止循环引用的最大深度
+const maxRedactDepth = 32
+
+// Redact 返回 v 的副本，结构体转换为以 json 标签为键的 map，标记了 sensitive:"true" 的字段替换为 Redacted
+func Redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return redactValue(reflect.ValueOf(v), 0)
+}
+
+func redactValue(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() || depth > maxRedactDepth {
+		return nil
+	}
+	if isInjectedType(v.Type()) {
+		return nil
+	}
+	// 自定义序列化的类型（例如 time.Time）原样保留，但包含敏感字段时仍然逐个字段处理，避免绕过脱敏
+	if (v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType)) && !hasSensitiveFields(v.Type()) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem(), depth+1)
+	case reflect.Struct:
+		fields := map[string]interface{}{}
+		redactStruct(v, fields, depth)
+		return fields
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i), depth+1)
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value(), depth+1)
+		}
+		return entries
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+	}
+	return v.Interface()
+}
+
+// 按类型缓存 hasSensitiveFields 的结果
+var sensitiveTypes sync.Map
+
+// hasSensitiveFields 判断类型中（包括嵌套的结构体、指针、切片和 map）是否有标记了 sensitive:"true" 的字段
+func hasSensitiveFields(t reflect.Type) bool {
+	if cached, ok := sensitiveTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := findSensitiveFields(t, map[reflect.Type]bool{})
+	sensitiveTypes.Store(t, result)
+	return result
+}
+
+func findSensitiveFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findSensitiveFields(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); IsSensitive(field) || findSensitiveFields(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactStruct 将结构体的导出字段写入 fields，匿名嵌入的结构体与 encoding/json 一样展开
+func redactStruct(v reflect.Value, fields map[string]interface{}, depth int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				redactStruct(value, fields, depth+1)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if IsSensitive(field) {
+			fields[name] = Redacted
+			continue
+		}
+		fields[name] = redactValue(value, depth+1)
+	}
+}
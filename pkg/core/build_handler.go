@@ -1,11 +1,15 @@
 package iz2go
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
-	"slices"
+	"runtime"
 	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,23 +21,45 @@ const (
 	FromHeader = "header"
 )
 
+// BuildHandler 构建处理器，Init 返回错误时 panic
 func BuildHandler(handler interface{}) *HandlerInfo {
+	info, err := TryBuildHandler(handler)
+	if err != nil {
+		panic(err)
+	}
+	return info
+}
+
+// TryBuildHandler 构建处理器，Init 返回的错误会直接返回
+func TryBuildHandler(handler interface{}) (*HandlerInfo, error) {
 	if handler == nil {
-		return nil
+		return nil, nil
 	}
 
 	executableMethod := CheckExecutable(handler)
 
-	HandleInit(handler)
+	if err := HandleInit(handler); err != nil {
+		return nil, fmt.Errorf("init %s: %w", reflect.TypeOf(handler), err)
+	}
 	method := ParseMethod(handler)
-	handlerFunc := ParseHandler(handler, executableMethod)
+	scope := ParseScope(handler)
+	handlerFunc := parseHandler(handler, executableMethod, scope)
 
 	return &HandlerInfo{
-		Method:   method,
-		Handler:  handlerFunc,
-		Request:  executableMethod.Type.In(1),
-		Response: executableMethod.Type.Out(0),
-	}
+		Method:       method,
+		Scope:        scope,
+		Handler:      handlerFunc,
+		HandlerName:  reflect.TypeOf(handler).String(),
+		Source:       handlerSource(handler, executableMethod),
+		Request:      executableMethod.Type.In(1),
+		Response:     getResponseType(executableMethod.Type),
+		Status:       ParseStatus(handler, getResponseType(executableMethod.Type)),
+		Produces:     ParseProduces(handler, getResponseType(executableMethod.Type)),
+		SparseFields: IsSparseFields(handler),
+		Errors:       ParseErrors(handler),
+		Shutdown:     ParseShutdown(handler),
+		WebSocket:    IsWebSocket(handler),
+	}, nil
 }
 
 func CheckExecutable(handler interface{}) reflect.Method {
@@ -51,17 +77,36 @@ func CheckExecutable(handler interface{}) reflect.Method {
 		panic("Execute method must have two and only two parameters")
 	}
 
-	// 检查Execute方法的返回值
-	if method.Type.NumOut() != 2 {
-		panic("Execute method must return two and only two values")
+	// 检查Execute方法的返回值，只返回错误时响应 204
+	if method.Type.NumOut() != 1 && method.Type.NumOut() != 2 {
+		panic("Execute method must return (Any, IError), (Any, error), IError or error")
 	}
-	errorType := method.Type.Out(1)
-	if !errorType.Implements(reflect.TypeOf((*IError)(nil)).Elem()) {
-		panic("Execute method must return (Any, IError)")
+	// 错误可以是 IError 也可以是普通的 error，后者会通过 ToIError 转换
+	errorType := method.Type.Out(method.Type.NumOut() - 1)
+	if !errorType.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+		panic("Execute method must return (Any, IError), (Any, error), IError or error")
 	}
 	return method
 }
 
+var noContentType = reflect.TypeOf(NoContent{})
+
+// getResponseType 获取 Execute 的响应类型，只返回 IError 时为 NoContent
+func getResponseType(methodType reflect.Type) reflect.Type {
+	if methodType.NumOut() == 1 {
+		return noContentType
+	}
+	return methodType.Out(0)
+}
+
+// splitResult 拆分 Execute 的返回值为响应和错误
+func splitResult(ret []reflect.Value) (reflect.Value, reflect.Value) {
+	if len(ret) == 1 {
+		return reflect.ValueOf(NoContent{}), ret[0]
+	}
+	return ret[0], ret[1]
+}
+
 func ParseMethod(handler interface{}) string {
 	if h, ok := handler.(IWithMethod); ok {
 		return h.GetMethod()
@@ -69,10 +114,29 @@ func ParseMethod(handler interface{}) string {
 	return "GET"
 }
 
-func HandleInit(handler interface{}) {
-	if h, ok := handler.(IWithInit); ok {
+func HandleInit(handler interface{}) error {
+	switch h := handler.(type) {
+	case IWithInit:
+		return h.Init()
+	case iWithLegacyInit:
 		h.Init()
 	}
+	return nil
+}
+
+// ParseShutdown 返回处理器的停止钩子。同一个 HandlerInfo 可以注册到多个路径或多个 Registry，
+// 每次注册都会添加停止钩子，因此返回的函数只在第一次调用时执行 Shutdown
+func ParseShutdown(handler interface{}) func(ctx context.Context) error {
+	if h, ok := handler.(IWithShutdown); ok {
+		var once sync.Once
+		return func(ctx context.Context) (err error) {
+			once.Do(func() {
+				err = h.Shutdown(ctx)
+			})
+			return err
+		}
+	}
+	return nil
 }
 
 func CheckDecorator(handler interface{}) ([]Decorator, bool) {
@@ -85,14 +149,14 @@ func CheckDecorator(handler interface{}) ([]Decorator, bool) {
 		return nil, false
 	}
 
-	// 检查Execute方法的签名
-	if method.Type.NumIn() > 0 {
+	// 检查Decorators方法的签名（第一个参数是接收者）
+	if method.Type.NumIn() > 1 {
 		return nil, false
 	}
 
-	// 检查Execute方法的返回值
+	// 检查Decorators方法的返回值
 	if method.Type.NumOut() != 1 ||
-		method.Type.Out(0).String() != "[]Decorator" {
+		method.Type.Out(0) != reflect.TypeOf([]Decorator{}) {
 		return nil, false
 	}
 	// 创建值并调用方法获取结果
@@ -102,49 +166,146 @@ func CheckDecorator(handler interface{}) ([]Decorator, bool) {
 }
 
 func ParseHandler(handler interface{}, executableMethod reflect.Method) gin.HandlerFunc {
+	return parseHandler(handler, executableMethod, ScopeSingleton)
+}
+
+func parseHandler(handler interface{}, executableMethod reflect.Method, scope Scope) gin.HandlerFunc {
 	decorators, ok := CheckDecorator(handler)
 	handlerFunc := executableMethod.Func
-	wrapperedHandlerFunc := WrapperHandlerFunc(reflect.ValueOf(handler), handlerFunc)
+	var wrapperedHandlerFunc gin.HandlerFunc
+	if IsWebSocket(handler) {
+		// 装饰器作用于升级连接的请求
+		wrapperedHandlerFunc = webSocketHandlerFunc(handler, scope, handlerFunc)
+	} else {
+		wrapperedHandlerFunc = wrapperHandlerFunc(handler, scope, handlerFunc)
+	}
 	if ok {
-		slices.Reverse(decorators)
-		for _, decorator := range decorators {
-			wrapperedHandlerFunc = decorator(wrapperedHandlerFunc)
-		}
-		return wrapperedHandlerFunc
+		return applyDecorators(wrapperedHandlerFunc, decorators)
 	}
 	return wrapperedHandlerFunc
 }
 
+// applyDecorators 按顺序应用装饰器，第一个装饰器在最外层，每个装饰器对应一个 span
+func applyDecorators(handlerFunc gin.HandlerFunc, decorators []Decorator) gin.HandlerFunc {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		name := runtime.FuncForPC(reflect.ValueOf(decorators[i]).Pointer()).Name()
+		handlerFunc = traceDecorator(name, decorators[i](handlerFunc))
+	}
+	return handlerFunc
+}
+
 func WrapperHandlerFunc(handler reflect.Value, handlerFunc reflect.Value) gin.HandlerFunc {
+	return wrapperHandlerFunc(handler.Interface(), ScopeSingleton, handlerFunc)
+}
+
+func wrapperHandlerFunc(handler interface{}, scope Scope, handlerFunc reflect.Value) gin.HandlerFunc {
+	instance := newInstanceProvider(handler, scope)
+	methodType := handlerFunc.Type()
+	return newHandlerFunc(handler, methodType.In(0).String(), methodType.In(1), getResponseType(methodType), instance,
+		func(c *gin.Context, api reflect.Value, request reflect.Value) []reflect.Value {
+			return handlerFunc.Call([]reflect.Value{api, request})
+		})
+}
+
+// newHandlerFunc 绑定请求参数、调用处理器并写出响应，call 返回值的形式与 Execute 相同。
+// instance 返回本次请求使用的处理器实例，GetCachePolicy、GetETag 和 Execute 都在同一个实例上调用；函数注册时为 nil
+func newHandlerFunc(handler interface{}, handlerName string, requestType reflect.Type, responseType reflect.Type,
+	instance func() reflect.Value, call func(c *gin.Context, api reflect.Value, request reflect.Value) []reflect.Value) gin.HandlerFunc {
+	_, isStream := streamElemType(responseType)
+	heartbeat := ParseHeartbeat(handler)
+	status := ParseStatus(handler, nil)
+	// 没有声明格式时总是返回 JSON，不协商
+	var produces []string
+	if _, ok := handler.(IWithProduces); ok {
+		produces = ParseProduces(handler, responseType)
+	}
+	_, withCachePolicy := handler.(IWithCachePolicy)
+	_, withETag := handler.(IWithETag)
+	sparseFields := IsSparseFields(handler)
 	return func(c *gin.Context) {
-		request := ParseRequest(c, handlerFunc.Type().In(1))
-		ret := handlerFunc.Call([]reflect.Value{handler, request})
-		var err IError
-		var ok bool
-		response := ret[0].Interface()
-		if !ret[1].IsNil() {
-			err, ok = ret[1].Interface().(IError)
-			if !ok {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "error must be IError"})
+		defer recoverHandler(c, handlerName)
+		// 在执行处理器之前协商，避免无法响应时仍然产生副作用
+		if !negotiate(c, produces) {
+			return
+		}
+		var api reflect.Value
+		if instance != nil {
+			api = instance()
+		}
+		if withCachePolicy {
+			c.Set(cachePolicyContextKey, ParseCachePolicy(api.Interface()))
+		}
+		if sparseFields {
+			c.Set(fieldsContextKey, true)
+		}
+		request := withSpan(c, "bind", func() reflect.Value {
+			request, err := parseRequest(c, requestType)
+			if err != nil {
+				c.Set(bindErrorContextKey, err)
+			}
+			return request
+		})
+		if hooks := engineFromContext(c).getRegistry().beforeHooks.load(); len(hooks) > 0 {
+			pointer := reflect.New(requestType)
+			pointer.Elem().Set(request)
+			if err := onBefore(c, hooks, pointer.Interface()); err != nil {
+				handleError(c, handlerName, err)
 				return
 			}
-		} else {
-			err = nil
+			request = pointer.Elem()
 		}
-
-		if err != nil {
-			OnError(c, err)
+		// 条件请求在执行前钩子之后检查，租户识别、鉴权等钩子拒绝的请求不会得到 304/412
+		if withETag && !checkPreconditions(c, api.Interface().(IWithETag)) {
+			return
+		}
+		if capture := getAuditCapture(c); capture != nil {
+			capture.request = request.Interface()
+		}
+		result, errValue := splitResult(withSpan(c, "execute", func() []reflect.Value {
+			return call(c, api, request)
+		}))
+		response := result.Interface()
+		if !errValue.IsNil() {
+			handleError(c, handlerName, errValue.Interface().(error))
+			return
+		}
+		if capture := getAuditCapture(c); capture != nil && !isStream {
+			capture.response = response
+		}
+		if isStream {
+			streamResponse(c, result, heartbeat)
 			return
 		}
-		OnSuccess(c, response)
+		response, code := resolveResponse(c, response, status)
+		if renderSpecialResponse(c, response) {
+			return
+		}
+		onSuccess(c, code, response)
 	}
 }
 
+// handleError 将错误转换为 IError，服务端错误记录日志后交给 OnError
+func handleError(c *gin.Context, handlerName string, err error) {
+	registry := engineFromContext(c).getRegistry()
+	e := registry.ToIError(err)
+	if registry.HTTPStatus(e) >= http.StatusInternalServerError {
+		logError(c, handlerName, e)
+	}
+	OnError(c, e)
+}
+
 func ParseRequest(c *gin.Context, requestType reflect.Type) reflect.Value {
+	request, _ := parseRequest(c, requestType)
+	return request
+}
+
+// parseRequest 绑定请求参数，无法解析的字段保持零值，返回的错误包含所有解析失败的字段
+func parseRequest(c *gin.Context, requestType reflect.Type) (reflect.Value, error) {
 	// 如果是 *gin.Context 类型，直接返回 context
 	if requestType == reflect.TypeOf(c) {
-		return reflect.ValueOf(c)
+		return reflect.ValueOf(c), nil
 	}
+	var errs []error
 
 	// 创建请求类型的新实例
 	request := reflect.New(requestType).Elem()
@@ -186,18 +347,24 @@ func ParseRequest(c *gin.Context, requestType reflect.Type) reflect.Value {
 				if value := getValueFromContext(c, from, mapping); value != "" {
 					if val, err := strconv.ParseInt(value, 10, 64); err == nil {
 						field.SetInt(val)
+					} else {
+						errs = append(errs, fmt.Errorf("%s: %w", mapping, err))
 					}
 				}
 			case reflect.Float32, reflect.Float64:
 				if value := getValueFromContext(c, from, mapping); value != "" {
 					if val, err := strconv.ParseFloat(value, 64); err == nil {
 						field.SetFloat(val)
+					} else {
+						errs = append(errs, fmt.Errorf("%s: %w", mapping, err))
 					}
 				}
 			case reflect.Bool:
 				if value := getValueFromContext(c, from, mapping); value != "" {
 					if val, err := strconv.ParseBool(value); err == nil {
 						field.SetBool(val)
+					} else {
+						errs = append(errs, fmt.Errorf("%s: %w", mapping, err))
 					}
 				}
 			case reflect.Struct:
@@ -205,12 +372,15 @@ func ParseRequest(c *gin.Context, requestType reflect.Type) reflect.Value {
 				jsonObj := reflect.New(fieldType.Type).Interface()
 				if err := c.ShouldBindJSON(jsonObj); err == nil {
 					field.Set(reflect.ValueOf(jsonObj).Elem())
+				} else if !errors.Is(err, io.EOF) {
+					// 没有请求体时不算解析失败
+					errs = append(errs, fmt.Errorf("%s: %w", fieldType.Name, err))
 				}
 			}
 		}
 	}
 
-	return request
+	return request, errors.Join(errs...)
 }
 
 // 辅助函数：根据来源获取值
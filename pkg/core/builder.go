@@ -0,0 +1,135 @@
+package iz2go
+
+import (
+	"html/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Builder 构建 Engine，默认使用一个新的 Registry，与包级别注册的路由和钩子互不影响
+//
+//	admin := iz2go.NewBuilder().
+//		Route("/admin/users", iz2go.BuildHandler(&ListUsers{})).
+//		ErrorHook(reportError).
+//		Build()
+type Builder struct {
+	registry  *Registry
+	router    *gin.Engine
+	accessLog bool
+	configure []func(e *Engine)
+}
+
+func NewBuilder() *Builder {
+	return &Builder{registry: NewRegistry()}
+}
+
+// WithRegistry 使用指定的 Registry，多个 Engine 可以共享同一个 Registry
+func (b *Builder) WithRegistry(registry *Registry) *Builder {
+	b.registry = registry
+	return b
+}
+
+// WithGin 使用指定的 gin.Engine，默认为 gin.Default()
+func (b *Builder) WithGin(router *gin.Engine) *Builder {
+	b.router = router
+	return b
+}
+
+// Registry 返回正在构建的 Engine 使用的 Registry，可以配合 RegisterSuccessHookTo 注册成功钩子
+func (b *Builder) Registry() *Registry {
+	return b.registry
+}
+
+// Route 注册路由，与已有路由冲突时 panic
+func (b *Builder) Route(path string, handler *HandlerInfo) *Builder {
+	if err := b.registry.RegisterRoute(path, handler); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (b *Builder) ErrorHook(hook func(ctx *gin.Context, err IError) (IError, bool), opts ...HookOption) *Builder {
+	b.registry.RegisterErrorHook(hook, opts...)
+	return b
+}
+
+// SuccessHook 注册对所有响应生效的成功钩子，只对某种类型生效的钩子使用 RegisterSuccessHookTo
+func (b *Builder) SuccessHook(hook func(ctx *gin.Context, response any) (any, bool), opts ...HookOption) *Builder {
+	RegisterSuccessHookTo(b.registry, hook, opts...)
+	return b
+}
+
+func (b *Builder) BeforeHook(hook func(ctx *gin.Context, route *HandlerInfo, request interface{}) IError, opts ...HookOption) *Builder {
+	b.registry.RegisterBeforeHook(hook, opts...)
+	return b
+}
+
+func (b *Builder) ErrorRenderer(renderer ErrorRenderer) *Builder {
+	return b.with(func(e *Engine) { e.SetErrorRenderer(renderer) })
+}
+
+func (b *Builder) Envelope(envelope *Envelope) *Builder {
+	return b.with(func(e *Engine) { e.SetEnvelope(envelope) })
+}
+
+func (b *Builder) Catalog(catalog *Catalog) *Builder {
+	return b.with(func(e *Engine) { e.SetCatalog(catalog) })
+}
+
+func (b *Builder) Tracing(config TracingConfig) *Builder {
+	return b.with(func(e *Engine) { e.UseTracing(config) })
+}
+
+func (b *Builder) Metrics(config MetricsConfig) *Builder {
+	return b.with(func(e *Engine) { e.UseMetrics(config) })
+}
+
+// AccessLog 使用 slog 记录访问日志，没有通过 WithGin 指定 gin.Engine 时不再使用 gin 的文本日志
+func (b *Builder) AccessLog(config AccessLogConfig) *Builder {
+	b.accessLog = true
+	return b.with(func(e *Engine) { e.UseAccessLog(config) })
+}
+
+func (b *Builder) Audit(config AuditConfig) *Builder {
+	return b.with(func(e *Engine) { e.UseAudit(config) })
+}
+
+func (b *Builder) ShutdownTimeout(timeout time.Duration) *Builder {
+	return b.with(func(e *Engine) { e.ShutdownTimeout = timeout })
+}
+
+func (b *Builder) with(configure func(e *Engine)) *Builder {
+	b.configure = append(b.configure, configure)
+	return b
+}
+
+// Build 创建 Engine 并注册 Registry 中已有的路由，之后注册到 Registry 的路由不会再添加到该 Engine，钩子则会立即生效
+func (b *Builder) Build() *Engine {
+	router := b.router
+	switch {
+	case router != nil:
+	case b.accessLog:
+		router = gin.New()
+		router.Use(gin.Recovery())
+	default:
+		router = gin.Default()
+	}
+	tmpl := template.Must(template.New("swagger").Parse(swaggerHTML))
+	router.SetHTMLTemplate(tmpl)
+	engine := &Engine{
+		Engine:   router,
+		registry: b.registry,
+	}
+	for _, configure := range b.configure {
+		configure(engine)
+	}
+	// 需要在注册路由之前添加，gin 只会将已有的中间件应用到新注册的路由上
+	router.Use(func(c *gin.Context) {
+		c.Set(engineContextKey, engine)
+	})
+	for _, route := range b.registry.Routes() {
+		router.Handle(route.Method, route.Path, withRoute(route))
+	}
+	return engine
+}
@@ -0,0 +1,234 @@
+package iz2go
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求上下文中保存缓存策略和处理器提供的 ETag 的键
+const (
+	cachePolicyContextKey = "iz2go.cache"
+	etagContextKey        = "iz2go.etag"
+)
+
+// CachePolicy 接口的缓存策略
+type CachePolicy struct {
+	// ETag 为 true 时对序列化后的响应内容计算弱 ETag，GET 请求的 If-None-Match 匹配时响应 304
+	ETag bool
+	// CacheControl 成功时写入 Cache-Control 响应头，例如 "private, max-age=60"
+	CacheControl string
+	// RequireIfMatch 为 true 时处理器实现 IWithETag 的 PUT、PATCH、DELETE 请求必须携带 If-Match，否则响应 428
+	RequireIfMatch bool
+}
+
+// IWithCachePolicy 处理器实现该接口指定缓存策略
+type IWithCachePolicy interface {
+	GetCachePolicy() CachePolicy
+}
+
+// IWithETag 处理器实现该接口在执行之前提供资源当前的 ETag：
+// GET 请求的 If-None-Match 匹配时不执行处理器直接响应 304，
+// PUT、PATCH、DELETE 请求的 If-Match 不匹配时响应 412。返回空字符串表示资源不存在
+type IWithETag interface {
+	GetETag(c *gin.Context) (string, IError)
+}
+
+// IWithLastModified 响应实现该接口时写入 Last-Modified 响应头，GET 请求的 If-Modified-Since 不早于该时间时响应 304
+type IWithLastModified interface {
+	GetLastModified() time.Time
+}
+
+func ParseCachePolicy(handler interface{}) *CachePolicy {
+	if h, ok := handler.(IWithCachePolicy); ok {
+		policy := h.GetCachePolicy()
+		return &policy
+	}
+	return nil
+}
+
+// WithCachePolicy 以装饰器的形式指定缓存策略，用于 Handle 注册的函数
+func WithCachePolicy(policy CachePolicy) Decorator {
+	return func(next gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(cachePolicyContextKey, &policy)
+			next(c)
+		}
+	}
+}
+
+func getCachePolicy(c *gin.Context) *CachePolicy {
+	if value, ok := c.Get(cachePolicyContextKey); ok {
+		return value.(*CachePolicy)
+	}
+	return nil
+}
+
+// checkPreconditions 在执行处理器之前检查 If-None-Match 和 If-Match，返回 false 时已经写出响应
+func checkPreconditions(c *gin.Context, handler IWithETag) bool {
+	method := c.Request.Method
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		etag, err := handler.GetETag(c)
+		if err != nil {
+			OnError(c, err)
+			return false
+		}
+		if etag == "" {
+			return true
+		}
+		etag = quoteETag(etag)
+		c.Set(etagContextKey, etag)
+		if matchETag(c.GetHeader("If-None-Match"), etag, false) {
+			c.Header("ETag", etag)
+			notModified(c)
+			return false
+		}
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		ifMatch := c.GetHeader("If-Match")
+		if ifMatch == "" {
+			if policy := getCachePolicy(c); policy != nil && policy.RequireIfMatch {
+				OnError(c, PreconditionRequired("If-Match header is required"))
+				return false
+			}
+			return true
+		}
+		etag, err := handler.GetETag(c)
+		if err != nil {
+			OnError(c, err)
+			return false
+		}
+		if etag == "" || (strings.TrimSpace(ifMatch) != "*" && !matchETag(ifMatch, quoteETag(etag), true)) {
+			OnError(c, PreconditionFailed("resource has been modified"))
+			return false
+		}
+	}
+	return true
+}
+
+// renderCached 写出 GET 请求的成功响应，按缓存策略计算 ETag 并处理条件请求
+func renderCached(c *gin.Context, status int, response interface{}) {
+	header := c.Writer.Header()
+	setCacheControl(c)
+	method := c.Request.Method
+	if status != http.StatusOK || (method != http.MethodGet && method != http.MethodHead) {
+		renderNegotiated(c, status, response)
+		return
+	}
+	if etag := c.GetString(etagContextKey); etag != "" && header.Get("ETag") == "" {
+		header.Set("ETag", etag)
+	}
+	if policy := getCachePolicy(c); policy == nil || !policy.ETag || header.Get("ETag") != "" {
+		if isNotModified(c) {
+			notModified(c)
+			return
+		}
+		renderNegotiated(c, status, response)
+		return
+	}
+
+	// 先序列化，得到响应内容才能计算 ETag
+	data, ok := encodeNegotiated(c, response)
+	if !ok {
+		return
+	}
+	sum := sha256.Sum256(data.Data)
+	header.Set("ETag", `W/"`+hex.EncodeToString(sum[:16])+`"`)
+	if isNotModified(c) {
+		notModified(c)
+		return
+	}
+	c.Render(status, data)
+}
+
+// isNotModified If-None-Match 存在时只比较 ETag，否则比较 If-Modified-Since 和 Last-Modified
+func isNotModified(c *gin.Context) bool {
+	header := c.Writer.Header()
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		etag := header.Get("ETag")
+		return etag != "" && matchETag(ifNoneMatch, etag, false)
+	}
+	ifModifiedSince, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
+func setCacheControl(c *gin.Context) {
+	if policy := getCachePolicy(c); policy != nil && policy.CacheControl != "" {
+		c.Header("Cache-Control", policy.CacheControl)
+	}
+}
+
+func notModified(c *gin.Context) {
+	setCacheControl(c)
+	header := c.Writer.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
+
+// matchETag 判断 ETag 是否在条件请求头的列表中，strong 为 true 时弱 ETag 不匹配
+func matchETag(condition string, etag string, strong bool) bool {
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(condition, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" && !strong {
+			return true
+		}
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag 处理器返回的 ETag 没有引号时补上
+func quoteETag(etag string) string {
+	if strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// bufferedWriter 暂存响应头和响应内容，不写出到客户端
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(int) {}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
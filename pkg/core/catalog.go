@@ -0,0 +1,456 @@
+package iz2go
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogEntry 一个错误码的定义，Messages 为语言到消息模板的映射，
+// 模板使用 fmt 格式，可以通过 %[n]v 调整参数顺序
+type CatalogEntry struct {
+	Code        int               `json:"code"`
+	Status      int               `json:"status,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Messages    map[string]string `json:"messages"`
+}
+
+// Catalog 错误目录，维护错误码在各语言下的消息模板
+type Catalog struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	entries       map[int]*CatalogEntry
+}
+
+type catalogFile struct {
+	DefaultLocale string         `json:"defaultLocale,omitempty"`
+	Errors        []CatalogEntry `json:"errors"`
+}
+
+// DefaultCatalog 返回包级别的默认错误目录，即 DefaultRegistry() 的错误目录
+func DefaultCatalog() *Catalog {
+	return defaultRegistry.Catalog()
+}
+
+// NewCatalog 创建错误目录，defaultLocale 为无法匹配请求语言时使用的语言
+func NewCatalog(defaultLocale string) *Catalog {
+	return &Catalog{
+		defaultLocale: defaultLocale,
+		entries:       make(map[int]*CatalogEntry),
+	}
+}
+
+// RegisterMessage 向默认错误目录注册消息模板
+func RegisterMessage(code int, locale string, template string) {
+	defaultRegistry.Catalog().Register(code, locale, template)
+}
+
+// Register 注册错误码在指定语言下的消息模板，第一个注册的语言作为默认语言
+func (c *Catalog) Register(code int, locale string, template string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entry(code)
+	entry.Messages[locale] = template
+	if c.defaultLocale == "" {
+		c.defaultLocale = locale
+	}
+}
+
+// SetStatus 设置错误码对应的 HTTP 状态码
+func (c *Catalog) SetStatus(code int, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry(code).Status = status
+}
+
+// SetDefaultLocale 设置无法匹配请求语言时使用的语言
+func (c *Catalog) SetDefaultLocale(locale string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultLocale = locale
+}
+
+func (c *Catalog) entry(code int) *CatalogEntry {
+	entry, ok := c.entries[code]
+	if !ok {
+		entry = &CatalogEntry{Code: code, Messages: make(map[string]string)}
+		c.entries[code] = entry
+	}
+	return entry
+}
+
+// Lookup 获取错误码的定义
+func (c *Catalog) Lookup(code int) (CatalogEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[code]
+	if !ok {
+		return CatalogEntry{}, false
+	}
+	return cloneEntry(entry), true
+}
+
+// Message 使用指定语言的模板格式化消息，locale 没有对应模板时使用默认语言
+func (c *Catalog) Message(code int, locale string, args ...interface{}) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[code]
+	if !ok {
+		return "", false
+	}
+	template, ok := entry.Messages[locale]
+	if !ok {
+		template, ok = entry.Messages[c.defaultLocale]
+	}
+	if !ok {
+		return "", false
+	}
+	if len(args) == 0 {
+		return template, true
+	}
+	return fmt.Sprintf(template, args...), true
+}
+
+// Locales 返回目录中出现过的所有语言，默认语言在最前
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]bool)
+	for _, entry := range c.entries {
+		for locale := range entry.Messages {
+			seen[locale] = true
+		}
+	}
+	locales := make([]string, 0, len(seen))
+	for locale := range seen {
+		if locale != c.defaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	if seen[c.defaultLocale] {
+		locales = append([]string{c.defaultLocale}, locales...)
+	}
+	return locales
+}
+
+// Entries 按错误码排序返回所有定义
+func (c *Catalog) Entries() []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries := make([]CatalogEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, cloneEntry(entry))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Code < entries[j].Code
+	})
+	return entries
+}
+
+func cloneEntry(entry *CatalogEntry) CatalogEntry {
+	clone := *entry
+	clone.Messages = make(map[string]string, len(entry.Messages))
+	for locale, template := range entry.Messages {
+		clone.Messages[locale] = template
+	}
+	return clone
+}
+
+// Negotiate 根据 Accept-Language 选择目录中支持的语言，
+// 先按权重精确匹配，再匹配主语言（zh 与 zh-CN），都不匹配时返回默认语言
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	locales := c.Locales()
+	c.mu.RLock()
+	defaultLocale := c.defaultLocale
+	c.mu.RUnlock()
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			return defaultLocale
+		}
+		for _, locale := range locales {
+			if strings.EqualFold(locale, tag) {
+				return locale
+			}
+		}
+		base := primaryLanguage(tag)
+		for _, locale := range locales {
+			if strings.EqualFold(primaryLanguage(locale), base) {
+				return locale
+			}
+		}
+	}
+	return defaultLocale
+}
+
+// parseAcceptLanguage 解析 Accept-Language，按权重从高到低返回语言标签
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: strings.TrimSpace(tag), q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = tag.tag
+	}
+	return result
+}
+
+func primaryLanguage(tag string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return strings.ToLower(base)
+}
+
+// NewCatalogError 创建使用默认错误目录消息的错误，args 为模板参数，
+// 返回给客户端时会按 Accept-Language 重新格式化
+func NewCatalogError(code int, args ...interface{}) IError {
+	return defaultRegistry.NewCatalogError(code, args...)
+}
+
+// IWithArgs IError 实现该接口时返回的参数用于格式化错误目录中的消息模板
+type IWithArgs interface {
+	GetArgs() []interface{}
+}
+
+// localizedError 使用错误目录中的消息替换原错误的消息
+type localizedError struct {
+	IError
+	message  string
+	status   int
+	registry *Registry
+}
+
+func (e *localizedError) Error() string {
+	return e.message
+}
+
+func (e *localizedError) GetMessage() string {
+	return e.message
+}
+
+func (e *localizedError) HTTPStatus() int {
+	if h, ok := e.IError.(IWithHTTPStatus); ok {
+		if status := h.HTTPStatus(); status != 0 {
+			return status
+		}
+	}
+	if e.status != 0 {
+		return e.status
+	}
+	return e.registry.HTTPStatus(e.IError)
+}
+
+func (e *localizedError) Unwrap() error {
+	return e.IError
+}
+
+// localize 根据请求的 Accept-Language 使用错误目录中的消息，目录中没有该错误码时原样返回
+func localize(c *gin.Context, engine *Engine, err IError) IError {
+	catalog := engine.getCatalog()
+	entry, ok := catalog.Lookup(err.GetCode())
+	if !ok {
+		return err
+	}
+	var args []interface{}
+	var withArgs IWithArgs
+	if errors.As(err, &withArgs) {
+		args = withArgs.GetArgs()
+	}
+	locale := catalog.Negotiate(c.GetHeader("Accept-Language"))
+	message, ok := catalog.Message(err.GetCode(), locale, args...)
+	if !ok {
+		return err
+	}
+	if locale != "" {
+		c.Header("Content-Language", locale)
+	}
+	return &localizedError{IError: err, message: message, status: entry.Status, registry: engine.getRegistry()}
+}
+
+// LoadCatalogFile 从文件加载默认错误目录，格式同 Catalog.LoadFile
+func LoadCatalogFile(path string) error {
+	return defaultRegistry.Catalog().LoadFile(path)
+}
+
+// LoadFile 从 JSON 或 CSV 文件加载错误目录，根据扩展名判断格式
+func (c *Catalog) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return c.LoadCSV(f)
+	}
+	return c.LoadJSON(f)
+}
+
+// LoadJSON 加载 WriteJSON 输出格式的错误目录
+func (c *Catalog) LoadJSON(r io.Reader) error {
+	var file catalogFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return err
+	}
+	if file.DefaultLocale != "" {
+		c.SetDefaultLocale(file.DefaultLocale)
+	}
+	for _, entry := range file.Errors {
+		c.add(entry)
+	}
+	return nil
+}
+
+// LoadCSV 加载从表格导出的错误目录，表头为 code、status、description 和各语言，
+// 例如 code,status,zh-CN,en,description；第一个语言列作为默认语言
+func (c *Catalog) LoadCSV(r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	header := records[0]
+	codeIndex := slices.IndexFunc(header, func(column string) bool {
+		return strings.EqualFold(strings.TrimSpace(column), "code")
+	})
+	if codeIndex < 0 {
+		return errors.New("catalog csv must have a code column")
+	}
+	for line, record := range records[1:] {
+		entry := CatalogEntry{Messages: make(map[string]string)}
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+			column := strings.TrimSpace(header[i])
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(column) {
+			case "code":
+				if entry.Code, err = strconv.Atoi(value); err != nil {
+					return fmt.Errorf("line %d: invalid code %q", line+2, value)
+				}
+			case "status":
+				if value != "" {
+					if entry.Status, err = strconv.Atoi(value); err != nil {
+						return fmt.Errorf("line %d: invalid status %q", line+2, value)
+					}
+				}
+			case "description":
+				entry.Description = value
+			default:
+				if value != "" {
+					entry.Messages[column] = value
+				}
+			}
+		}
+		c.add(entry)
+	}
+	for _, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "code", "status", "description":
+			continue
+		}
+		c.mu.Lock()
+		if c.defaultLocale == "" {
+			c.defaultLocale = strings.TrimSpace(column)
+		}
+		c.mu.Unlock()
+		break
+	}
+	return nil
+}
+
+func (c *Catalog) add(entry CatalogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing := c.entry(entry.Code)
+	if entry.Status != 0 {
+		existing.Status = entry.Status
+	}
+	if entry.Description != "" {
+		existing.Description = entry.Description
+	}
+	for locale, template := range entry.Messages {
+		existing.Messages[locale] = template
+	}
+}
+
+// WriteJSON 以 JSON 格式导出错误目录
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	c.mu.RLock()
+	defaultLocale := c.defaultLocale
+	c.mu.RUnlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(catalogFile{
+		DefaultLocale: defaultLocale,
+		Errors:        c.Entries(),
+	})
+}
+
+// WriteMarkdown 以 Markdown 表格导出错误目录
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	locales := c.Locales()
+	var b strings.Builder
+	b.WriteString("| Code | Status |")
+	for _, locale := range locales {
+		b.WriteString(" " + locale + " |")
+	}
+	b.WriteString(" Description |\n|---|---|")
+	for range locales {
+		b.WriteString("---|")
+	}
+	b.WriteString("---|\n")
+	for _, entry := range c.Entries() {
+		status := ""
+		if entry.Status != 0 {
+			status = strconv.Itoa(entry.Status)
+		}
+		fmt.Fprintf(&b, "| %d | %s |", entry.Code, status)
+		for _, locale := range locales {
+			b.WriteString(" " + escapeMarkdownCell(entry.Messages[locale]) + " |")
+		}
+		b.WriteString(" " + escapeMarkdownCell(entry.Description) + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
@@ -0,0 +1,82 @@
+package iz2go
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RouteConflictError 两个路由无法同时注册到 gin
+type RouteConflictError struct {
+	Method         string
+	Path           string
+	Source         string
+	ExistingPath   string
+	ExistingSource string
+	Reason         string
+}
+
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("route conflict: %s %s (%s) and %s %s (%s): %s",
+		e.Method, e.Path, sourceOrUnknown(e.Source), e.Method, e.ExistingPath, sourceOrUnknown(e.ExistingSource), e.Reason)
+}
+
+func sourceOrUnknown(source string) string {
+	if source == "" {
+		return "unknown source"
+	}
+	return source
+}
+
+// ConflictReason 判断同一请求方法下的两个路由路径能否同时注册到 gin，可以时返回空字符串。规则与 gin 一致：
+// 相同位置的路径参数名称必须相同，通配符 *name 所在的位置不能有其他路由，静态路径和路径参数可以共存
+func ConflictReason(path string, other string) string {
+	if path == other {
+		return "duplicate route"
+	}
+	segments, otherSegments := strings.Split(path, "/"), strings.Split(other, "/")
+	for i := 0; i < len(segments) && i < len(otherSegments); i++ {
+		segment, otherSegment := segments[i], otherSegments[i]
+		switch {
+		case strings.HasPrefix(segment, "*") || strings.HasPrefix(otherSegment, "*"):
+			if segment != otherSegment || i != len(segments)-1 || i != len(otherSegments)-1 {
+				return fmt.Sprintf("catch-all wildcard conflicts with other routes at the same position (%q and %q)", segment, otherSegment)
+			}
+		case strings.HasPrefix(segment, ":") && strings.HasPrefix(otherSegment, ":"):
+			if segment != otherSegment {
+				return fmt.Sprintf("wildcard %q conflicts with %q at the same position", segment, otherSegment)
+			}
+		case segment != otherSegment:
+			return ""
+		}
+	}
+	return ""
+}
+
+// handlerSource 返回处理器 Execute 方法定义的位置
+func handlerSource(handler interface{}, method reflect.Method) string {
+	if source := funcSource(method.Func); source != "" {
+		return source
+	}
+	// 指针接收者调用值接收者的方法时是编译器生成的包装函数，改为查找值类型上的方法
+	if t := reflect.TypeOf(handler); t.Kind() == reflect.Ptr {
+		if m, ok := t.Elem().MethodByName(method.Name); ok {
+			return funcSource(m.Func)
+		}
+	}
+	return ""
+}
+
+// funcSource 返回函数定义的文件和行号
+func funcSource(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return ""
+	}
+	file, line := f.FileLine(f.Entry())
+	if file == "" || strings.HasPrefix(file, "<") {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
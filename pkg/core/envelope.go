@@ -0,0 +1,91 @@
+package iz2go
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope 统一的响应包装，例如 {"code":0,"data":...,"msg":"ok"}，
+// 成功时 data 为 Execute 的返回值，失败时 code 和 msg 为错误码和错误消息，data 为 null
+type Envelope struct {
+	CodeKey        string
+	DataKey        string
+	MessageKey     string
+	SuccessCode    int
+	SuccessMessage string
+}
+
+// DefaultEnvelope 返回 {"code":0,"data":...,"msg":"ok"} 形式的响应包装
+func DefaultEnvelope() *Envelope {
+	return &Envelope{
+		CodeKey:        "code",
+		DataKey:        "data",
+		MessageKey:     "msg",
+		SuccessCode:    0,
+		SuccessMessage: "ok",
+	}
+}
+
+func (e *Envelope) keys() (string, string, string) {
+	codeKey, dataKey, messageKey := e.CodeKey, e.DataKey, e.MessageKey
+	if codeKey == "" {
+		codeKey = "code"
+	}
+	if dataKey == "" {
+		dataKey = "data"
+	}
+	if messageKey == "" {
+		messageKey = "msg"
+	}
+	return codeKey, dataKey, messageKey
+}
+
+// Wrap 包装成功的响应
+func (e *Envelope) Wrap(data interface{}) gin.H {
+	codeKey, dataKey, messageKey := e.keys()
+	return gin.H{
+		codeKey:    e.SuccessCode,
+		dataKey:    data,
+		messageKey: e.SuccessMessage,
+	}
+}
+
+// WrapError 包装错误响应
+func (e *Envelope) WrapError(err IError) gin.H {
+	codeKey, dataKey, messageKey := e.keys()
+	return gin.H{
+		codeKey:    err.GetCode(),
+		dataKey:    nil,
+		messageKey: err.GetMessage(),
+	}
+}
+
+// Definition 生成包装后的文档定义，data 为响应内容的 schema
+func (e *Envelope) Definition(data Property) Definition {
+	codeKey, dataKey, messageKey := e.keys()
+	return Definition{
+		Type: "object",
+		Properties: map[string]Property{
+			codeKey:    {Type: "integer"},
+			dataKey:    data,
+			messageKey: {Type: "string"},
+		},
+		Required: []string{codeKey, messageKey},
+	}
+}
+
+// envelopeErrorRenderer 设置了响应包装且没有指定错误渲染方式时使用
+type envelopeErrorRenderer struct {
+	envelope *Envelope
+}
+
+func (r envelopeErrorRenderer) Render(c *gin.Context, status int, err IError) {
+	c.JSON(status, r.envelope.WrapError(err))
+}
+
+func (r envelopeErrorRenderer) Definition() Definition {
+	return r.envelope.Definition(Property{Type: "object"})
+}
+
+func (r envelopeErrorRenderer) ContentType() string {
+	return gin.MIMEJSON
+}
@@ -0,0 +1,119 @@
+package iz2go
+
+import (
+	"net/http"
+)
+
+// RegisterStatusCode 向 DefaultRegistry() 注册业务错误码对应的 HTTP 状态码，
+// 错误没有通过 HTTPStatus 指定状态码时使用
+func RegisterStatusCode(code int, status int) {
+	defaultRegistry.RegisterStatusCode(code, status)
+}
+
+// GetHTTPStatus 获取错误对应的 HTTP 状态码：优先使用 HTTPStatus，
+// 其次是 DefaultRegistry() 中 RegisterStatusCode 注册的映射，都没有时为 500
+func GetHTTPStatus(err IError) int {
+	return defaultRegistry.HTTPStatus(err)
+}
+
+// NewHTTPError 创建指定 HTTP 状态码的错误
+func NewHTTPError(status int, code int, message string) IError {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+// 以下构造函数的业务错误码与 HTTP 状态码相同
+
+func BadRequest(message string) IError {
+	return NewHTTPError(http.StatusBadRequest, http.StatusBadRequest, message)
+}
+
+func Unauthorized(message string) IError {
+	return NewHTTPError(http.StatusUnauthorized, http.StatusUnauthorized, message)
+}
+
+func Forbidden(message string) IError {
+	return NewHTTPError(http.StatusForbidden, http.StatusForbidden, message)
+}
+
+func NotFound(message string) IError {
+	return NewHTTPError(http.StatusNotFound, http.StatusNotFound, message)
+}
+
+func Conflict(message string) IError {
+	return NewHTTPError(http.StatusConflict, http.StatusConflict, message)
+}
+
+func PreconditionFailed(message string) IError {
+	return NewHTTPError(http.StatusPreconditionFailed, http.StatusPreconditionFailed, message)
+}
+
+func UnprocessableEntity(message string) IError {
+	return NewHTTPError(http.StatusUnprocessableEntity, http.StatusUnprocessableEntity, message)
+}
+
+func PreconditionRequired(message string) IError {
+	return NewHTTPError(http.StatusPreconditionRequired, http.StatusPreconditionRequired, message)
+}
+
+func TooManyRequests(message string) IError {
+	return NewHTTPError(http.StatusTooManyRequests, http.StatusTooManyRequests, message)
+}
+
+func InternalError(message string) IError {
+	return NewHTTPError(http.StatusInternalServerError, http.StatusInternalServerError, message)
+}
+
+func ServiceUnavailable(message string) IError {
+	return NewHTTPError(http.StatusServiceUnavailable, http.StatusServiceUnavailable, message)
+}
+
+func ParseErrors(handler interface{}) []IError {
+	if h, ok := handler.(IWithErrors); ok {
+		return h.GetErrors()
+	}
+	return nil
+}
+
+// WrappedError 由普通 error 转换得到的 IError，返回给客户端的是 IError 的内容，
+// Cause 保存原始错误用于日志和 errors.Is/errors.As，不会返回给客户端
+type WrappedError struct {
+	IError
+	Cause error
+
+	// registry 为进行转换的 Registry，用于查找状态码映射
+	registry *Registry
+}
+
+func (e *WrappedError) HTTPStatus() int {
+	if e.registry == nil {
+		return GetHTTPStatus(e.IError)
+	}
+	return e.registry.HTTPStatus(e.IError)
+}
+
+// Unwrap 同时返回转换后的 IError 和原始错误，errors.As 可以取到两者实现的接口
+func (e *WrappedError) Unwrap() []error {
+	return []error{e.IError, e.Cause}
+}
+
+// RegisterErrorMapping 向 DefaultRegistry() 注册哨兵错误到 IError 的映射，通过 errors.Is 匹配，
+// 例如 RegisterErrorMapping(sql.ErrNoRows, NotFound("record not found"))
+func RegisterErrorMapping(target error, to IError) {
+	defaultRegistry.RegisterErrorMapping(target, to)
+}
+
+// RegisterErrorType 向 DefaultRegistry() 注册错误类型到 IError 的映射，通过 errors.As 匹配
+func RegisterErrorType[E error](mapper func(err E) IError) {
+	RegisterErrorTypeTo(defaultRegistry, mapper)
+}
+
+// ToIError 将 error 转换为 IError：错误链中有 IError 时直接使用，
+// 其次按注册顺序匹配 DefaultRegistry() 中 RegisterErrorMapping/RegisterErrorType 注册的映射，
+// 都不匹配时转换为 500 内部错误；原始错误保存在 WrappedError.Cause 中
+func ToIError(err error) IError {
+	return defaultRegistry.ToIError(err)
+}
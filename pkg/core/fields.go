@@ -0,0 +1,117 @@
+package iz2go
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// FieldsParam 指定返回字段的查询参数，例如 ?fields=id,name,owner.email
+	FieldsParam = "fields"
+
+	fieldsContextKey = "iz2go.fields"
+)
+
+// SparseFields 嵌入到处理器中即可支持 ?fields= 只返回指定的字段，用法同 *iz2go.Get
+type SparseFields struct{}
+
+func (s *SparseFields) isSparseFields() {}
+
+type iSparseFields interface {
+	isSparseFields()
+}
+
+func IsSparseFields(handler interface{}) bool {
+	_, ok := handler.(iSparseFields)
+	return ok
+}
+
+// WithSparseFields 以装饰器的形式支持 ?fields=，用于 Handle 注册的函数
+func WithSparseFields() Decorator {
+	return func(next gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(fieldsContextKey, true)
+			next(c)
+		}
+	}
+}
+
+// fieldSet 字段路径组成的树，叶子节点为 nil 表示选择整个字段
+type fieldSet map[string]fieldSet
+
+// parseFields 解析逗号分隔的字段路径，同时选择了 owner 和 owner.email 时以 owner 为准
+func parseFields(fields string) fieldSet {
+	set := fieldSet{}
+	for _, path := range strings.Split(fields, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		node := set
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			child, exists := node[name]
+			if exists && child == nil {
+				break
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if !exists {
+				child = fieldSet{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return set
+}
+
+// selectFields 按请求的 ?fields= 裁剪响应，字段名以 json 标签为准。
+// 裁剪后的响应是 map，XML 和 protobuf 无法序列化 map，这两种格式不裁剪
+func selectFields(c *gin.Context, response interface{}) interface{} {
+	if !c.GetBool(fieldsContextKey) || response == nil {
+		return response
+	}
+	switch c.GetString(formatContextKey) {
+	case MIMEXML, MIMEPROTOBUF:
+		return response
+	}
+	set := parseFields(c.Query(FieldsParam))
+	if len(set) == 0 {
+		return response
+	}
+	// 数字转换为 int64 或 float64，YAML 和 msgpack 中不会变成字符串
+	value, err := toJSONValue(response)
+	if err != nil {
+		return response
+	}
+	return set.apply(value)
+}
+
+func (set fieldSet) apply(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{}, len(set))
+		for name, child := range set {
+			field, ok := v[name]
+			if !ok {
+				continue
+			}
+			if child == nil {
+				selected[name] = field
+			} else {
+				selected[name] = child.apply(field)
+			}
+		}
+		return selected
+	case []interface{}:
+		for i, item := range v {
+			v[i] = set.apply(item)
+		}
+		return v
+	}
+	return value
+}
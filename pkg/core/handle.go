@@ -0,0 +1,61 @@
+package iz2go
+
+import (
+	"reflect"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handle 以函数的形式注册路由，与已有路由冲突时 panic。请求和响应类型在编译期确定，
+// 与结构体 + Execute 的处理器使用同一张路由表，同样会出现在 Default() 和 GenerateSwagger 中
+//
+//	iz2go.Handle(http.MethodGet, "/users/:id", func(c *gin.Context, req struct {
+//		ID int `from:"path" mapping:"id"`
+//	}) (User, iz2go.IError) {
+//		return users.Get(req.ID)
+//	})
+func Handle[Req, Resp any](method string, path string, fn func(ctx *gin.Context, request Req) (Resp, IError), decorators ...Decorator) *HandlerInfo {
+	info := BuildHandlerFunc(method, fn, decorators...)
+	info.Path = path
+	if err := RegisterRoute(path, info); err != nil {
+		panic(err)
+	}
+	return info
+}
+
+// BuildHandlerFunc 将函数构建为 HandlerInfo，decorators 的顺序与 Decorators 方法返回值的顺序相同
+func BuildHandlerFunc[Req, Resp any](method string, fn func(ctx *gin.Context, request Req) (Resp, IError), decorators ...Decorator) *HandlerInfo {
+	if fn == nil {
+		return nil
+	}
+	if method == "" {
+		method = "GET"
+	}
+	requestType := reflect.TypeFor[Req]()
+	responseType := reflect.TypeFor[Resp]()
+	handlerName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+
+	handlerFunc := newHandlerFunc(nil, handlerName, requestType, responseType, nil,
+		func(c *gin.Context, api reflect.Value, request reflect.Value) []reflect.Value {
+			// Req 为接口类型（例如 any）时 request 是 nil 接口，不能直接断言
+			var req Req
+			if value := request.Interface(); value != nil {
+				req = value.(Req)
+			}
+			response, err := fn(c, req)
+			return []reflect.Value{reflect.ValueOf(&response).Elem(), reflect.ValueOf(&err).Elem()}
+		})
+	handlerFunc = applyDecorators(handlerFunc, decorators)
+
+	return &HandlerInfo{
+		Method:      method,
+		Handler:     handlerFunc,
+		HandlerName: handlerName,
+		Source:      funcSource(reflect.ValueOf(fn)),
+		Request:     requestType,
+		Response:    responseType,
+		Status:      ParseStatus(nil, responseType),
+		Produces:    ParseProduces(nil, responseType),
+	}
+}
@@ -1,15 +1,34 @@
 package iz2go
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/gin-gonic/gin"
 )
 
 type HandlerInfo struct {
-	Method   string
-	Handler  gin.HandlerFunc
-	ApiName  string
+	Method string
+	// Path 注册的路由路径，例如 /users/:id，RegisterRoute 保存的副本上会设置该字段
+	Path    string
+	Scope   Scope
+	Handler gin.HandlerFunc
+	ApiName string
+	// HandlerName 处理器的类型名称，函数注册时为函数名，用于日志
+	HandlerName string
+	// Source 处理器定义的位置，用于报告路由冲突
+	Source   string
 	Request  reflect.Type
 	Response reflect.Type
+	// Status 成功时的状态码，用于生成文档
+	Status int
+	// Produces 支持的响应格式，为空时不进行内容协商
+	Produces []string
+	// SparseFields 为 true 时支持 ?fields= 只返回指定的字段
+	SparseFields bool
+	// Errors 处理器声明可能返回的错误，用于生成文档
+	Errors   []IError
+	Shutdown func(ctx context.Context) error
+	// WebSocket 为 true 时 Request 和 Response 分别是入站和出站消息的类型
+	WebSocket bool
 }
@@ -0,0 +1,94 @@
+package iz2go
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+type hookOptions struct {
+	name     string
+	priority int
+}
+
+// HookOption 注册钩子时的选项
+type HookOption func(*hookOptions)
+
+// WithHookName 为钩子命名，再次注册同名的钩子会替换原来的钩子，也可以按名称删除
+func WithHookName(name string) HookOption {
+	return func(o *hookOptions) {
+		o.name = name
+	}
+}
+
+// WithHookPriority 指定钩子的优先级，优先级高的先调用，默认为 0；
+// 优先级相同时后注册的先调用
+func WithHookPriority(priority int) HookOption {
+	return func(o *hookOptions) {
+		o.priority = priority
+	}
+}
+
+type hookEntry[H any] struct {
+	hookOptions
+	seq  uint64
+	hook H
+}
+
+// hookChain 按调用顺序排好的钩子链。注册和删除时复制出新的切片再原子替换，
+// 请求中读取到的切片不会再被修改，因此服务启动之后注册钩子也是安全的
+type hookChain[H any] struct {
+	mu      sync.Mutex
+	seq     uint64
+	entries atomic.Pointer[[]hookEntry[H]]
+}
+
+func (chain *hookChain[H]) add(hook H, opts []HookOption) {
+	entry := hookEntry[H]{hook: hook}
+	for _, opt := range opts {
+		opt(&entry.hookOptions)
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	chain.seq++
+	entry.seq = chain.seq
+	entries := slices.Clone(chain.load())
+	if entry.name != "" {
+		entries = slices.DeleteFunc(entries, func(e hookEntry[H]) bool {
+			return e.name == entry.name
+		})
+	}
+	entries = append(entries, entry)
+	slices.SortFunc(entries, func(a, b hookEntry[H]) int {
+		if a.priority != b.priority {
+			return cmp.Compare(b.priority, a.priority)
+		}
+		return cmp.Compare(b.seq, a.seq)
+	})
+	chain.entries.Store(&entries)
+}
+
+func (chain *hookChain[H]) remove(name string) bool {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	entries := chain.load()
+	index := slices.IndexFunc(entries, func(e hookEntry[H]) bool {
+		return e.name == name
+	})
+	if index < 0 {
+		return false
+	}
+	entries = slices.Delete(slices.Clone(entries), index, index+1)
+	chain.entries.Store(&entries)
+	return true
+}
+
+// load 返回当前的钩子链，调用方不能修改返回的切片
+func (chain *hookChain[H]) load() []hookEntry[H] {
+	if entries := chain.entries.Load(); entries != nil {
+		return *entries
+	}
+	return nil
+}
@@ -3,50 +3,124 @@ package iz2go
 import (
 	"net/http"
 	"reflect"
-	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
-var errorHooks []func(ctx *gin.Context, err IError) (IError, bool)
-var successHooks []func(ctx *gin.Context, response gin.H) (gin.H, bool)
+// successHook 返回值 matched 为 false 时表示响应类型不匹配，没有调用钩子
+type successHook func(ctx *gin.Context, response interface{}) (result interface{}, abort bool, matched bool)
+
+// onBefore 依次调用执行前钩子，request 为指向请求参数的指针，返回的错误会中断请求
+func onBefore(c *gin.Context, hooks []hookEntry[beforeHook], request interface{}) IError {
+	route := RouteFromContext(c)
+	for _, entry := range hooks {
+		if err := entry.hook(c, route, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrorFromContext 获取当前请求交给 OnError 处理的错误，没有错误时返回 nil
+func ErrorFromContext(c *gin.Context) IError {
+	if value, ok := c.Get(errorContextKey); ok {
+		if err, ok := value.(IError); ok {
+			return err
+		}
+	}
+	return nil
+}
 
 func OnError(c *gin.Context, err IError) {
+	engine := engineFromContext(c)
+	err = localize(c, engine, err)
+	c.Set(errorContextKey, err)
 	abort := false
-	hooks := errorHooks
-	slices.Reverse(hooks)
-	for _, hook := range hooks {
-		err, abort = hook(c, err)
-		if abort {
+	for _, entry := range engine.getRegistry().errorHooks.load() {
+		// 返回 nil 时没有可以继续处理的错误，等同于中断
+		if err, abort = entry.hook(c, err); abort || err == nil {
 			return
 		}
 	}
-	c.JSON(http.StatusInternalServerError, gin.H{"code": err.GetCode(), "message": err.GetMessage()})
+	engine.getErrorRenderer().Render(c, engine.getRegistry().HTTPStatus(err), err)
 }
 
 func OnSuccess(c *gin.Context, response interface{}) {
+	onSuccess(c, http.StatusOK, response)
+}
+
+func onSuccess(c *gin.Context, status int, response interface{}) {
 	abort := false
-	hooks := successHooks
-	slices.Reverse(hooks)
-	for _, hook := range hooks {
-		if reflect.TypeOf(response) == reflect.TypeOf(reflect.TypeOf(hook).In(1)) {
-			ret := reflect.ValueOf(hook).Call([]reflect.Value{reflect.ValueOf(c), reflect.ValueOf(response)})
-			response = ret[0].Interface()
-			if !ret[1].IsNil() {
-				abort = ret[1].Interface().(bool)
-			}
-			if abort {
-				return
-			}
+	for _, entry := range engineFromContext(c).getRegistry().successHooks.load() {
+		var matched bool
+		if response, abort, matched = entry.hook(c, response); matched && abort {
+			return
 		}
 	}
-	c.JSON(http.StatusOK, response)
+	if !bodyAllowedForStatus(status) {
+		c.Status(status)
+		return
+	}
+	response = selectFields(c, response)
+	if envelope := engineFromContext(c).getEnvelope(); envelope != nil {
+		response = envelope.Wrap(response)
+	}
+	renderCached(c, status, response)
+}
+
+// RegisterErrorHook 注册错误钩子，调用顺序见 WithHookPriority，返回 true 或 nil 时不再写出错误响应
+func RegisterErrorHook(hook func(ctx *gin.Context, err IError) (IError, bool), opts ...HookOption) {
+	defaultRegistry.RegisterErrorHook(hook, opts...)
+}
+
+// RemoveErrorHook 删除通过 WithHookName 命名的错误钩子
+func RemoveErrorHook(name string) bool {
+	return defaultRegistry.RemoveErrorHook(name)
+}
+
+// RegisterBeforeHook 注册执行前钩子，在绑定请求参数之后、调用 Execute 之前调用。
+// route 为当前路由，不是通过 RegisterRoute 注册时为 nil；request 为指向请求参数的指针，修改后会传给 Execute；
+// 返回错误时不再调用 Execute，错误交给 OnError 处理
+func RegisterBeforeHook(hook func(ctx *gin.Context, route *HandlerInfo, request interface{}) IError, opts ...HookOption) {
+	defaultRegistry.RegisterBeforeHook(hook, opts...)
 }
 
-func RegisterErrorHook(hook func(ctx *gin.Context, err IError) (IError, bool)) {
-	errorHooks = append(errorHooks, hook)
+// RemoveBeforeHook 删除通过 WithHookName 命名的执行前钩子
+func RemoveBeforeHook(name string) bool {
+	return defaultRegistry.RemoveBeforeHook(name)
 }
 
-func RegisterSuccessHook(hook func(ctx *gin.Context, response gin.H) (gin.H, bool)) {
-	successHooks = append(successHooks, hook)
+// RegisterSuccessHook 注册成功钩子，响应可以赋值给 T 时才会调用，T 为接口时对实现了该接口的响应生效，
+// T 为 any 时对所有响应生效。钩子返回的值替换原来的响应，返回 true 时不再写出响应
+func RegisterSuccessHook[T any](hook func(ctx *gin.Context, response T) (T, bool), opts ...HookOption) {
+	RegisterSuccessHookTo(defaultRegistry, hook, opts...)
+}
+
+// RemoveSuccessHook 删除通过 WithHookName 命名的成功钩子
+func RemoveSuccessHook(name string) bool {
+	return defaultRegistry.RemoveSuccessHook(name)
+}
+
+func newSuccessHook[T any](hook func(ctx *gin.Context, response T) (T, bool)) successHook {
+	target := reflect.TypeFor[T]()
+	return func(c *gin.Context, response interface{}) (interface{}, bool, bool) {
+		value, ok := response.(T)
+		if !ok {
+			v := reflect.ValueOf(response)
+			switch {
+			case !v.IsValid():
+				// nil 响应只匹配 any
+				if target.Kind() != reflect.Interface || target.NumMethod() != 0 {
+					return response, false, false
+				}
+			case v.Type().AssignableTo(target):
+				// 例如 map[string]interface{} 和 gin.H
+				value = v.Convert(target).Interface().(T)
+			default:
+				return response, false, false
+			}
+		}
+		result, abort := hook(c, value)
+		return result, abort, true
+	}
 }
@@ -0,0 +1,138 @@
+package iz2go
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type hookUser struct {
+	Name string `json:"name"`
+}
+
+func (u hookUser) String() string {
+	return u.Name
+}
+
+// serveWithHooks 注册一个返回 response 的路由，先调用 register 注册钩子，再发送请求
+func serveWithHooks[Resp any](t *testing.T, response Resp, register func(registry *Registry)) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	builder := NewBuilder().Route("/", BuildHandlerFunc(http.MethodGet, func(c *gin.Context, request struct{}) (Resp, IError) {
+		return response, nil
+	}))
+	register(builder.Registry())
+	w := httptest.NewRecorder()
+	builder.Build().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestSuccessHookConcreteType(t *testing.T) {
+	w := serveWithHooks(t, hookUser{Name: "alice"}, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, user hookUser) (hookUser, bool) {
+			user.Name = "bob"
+			return user, false
+		})
+	})
+	if body := w.Body.String(); body != `{"name":"bob"}` {
+		t.Fatalf("body = %s, want the response replaced by the hook", body)
+	}
+}
+
+func TestSuccessHookInterfaceType(t *testing.T) {
+	var got string
+	serveWithHooks(t, hookUser{Name: "alice"}, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, s fmt.Stringer) (fmt.Stringer, bool) {
+			got = s.String()
+			return s, false
+		})
+	})
+	if got != "alice" {
+		t.Fatalf("fmt.Stringer hook got %q, want alice", got)
+	}
+}
+
+func TestSuccessHookAnyReceivesNil(t *testing.T) {
+	called, userCalled := false, false
+	serveWithHooks[any](t, nil, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, response any) (any, bool) {
+			called = response == nil
+			return response, false
+		})
+		RegisterSuccessHookTo(registry, func(c *gin.Context, s fmt.Stringer) (fmt.Stringer, bool) {
+			userCalled = true
+			return s, false
+		})
+	})
+	if !called {
+		t.Fatal("any hook was not called with the nil response")
+	}
+	if userCalled {
+		t.Fatal("interface hook was called with the nil response")
+	}
+}
+
+func TestSuccessHookMapConversion(t *testing.T) {
+	var gotMap, gotH bool
+	serveWithHooks(t, gin.H{"a": 1}, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, m map[string]interface{}) (map[string]interface{}, bool) {
+			gotMap = m["a"] == 1
+			return m, false
+		})
+	})
+	serveWithHooks(t, map[string]interface{}{"a": 1}, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, h gin.H) (gin.H, bool) {
+			gotH = h["a"] == 1
+			return h, false
+		})
+	})
+	if !gotMap {
+		t.Fatal("map[string]interface{} hook did not match a gin.H response")
+	}
+	if !gotH {
+		t.Fatal("gin.H hook did not match a map[string]interface{} response")
+	}
+}
+
+func TestSuccessHookNoMatch(t *testing.T) {
+	called := false
+	w := serveWithHooks(t, "text", func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, n int) (int, bool) {
+			called = true
+			return n, true
+		})
+		RegisterSuccessHookTo(registry, func(c *gin.Context, user hookUser) (hookUser, bool) {
+			called = true
+			return user, true
+		})
+	})
+	if called {
+		t.Fatal("hook was called for a response of another type")
+	}
+	if body := w.Body.String(); body != `"text"` {
+		t.Fatalf("body = %s, want the original response", body)
+	}
+}
+
+func TestSuccessHookAbort(t *testing.T) {
+	laterCalled := false
+	w := serveWithHooks(t, hookUser{Name: "alice"}, func(registry *Registry) {
+		RegisterSuccessHookTo(registry, func(c *gin.Context, response any) (any, bool) {
+			laterCalled = true
+			return response, false
+		}, WithHookPriority(0))
+		RegisterSuccessHookTo(registry, func(c *gin.Context, user hookUser) (hookUser, bool) {
+			c.Status(http.StatusAccepted)
+			return user, true
+		}, WithHookPriority(10))
+	})
+	if laterCalled {
+		t.Fatal("hook after the aborting hook was called")
+	}
+	if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Fatalf("got %d %q, want the aborting hook's status and no body", w.Code, w.Body.String())
+	}
+}
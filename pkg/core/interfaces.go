@@ -1,13 +1,29 @@
 package iz2go
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 type Decorator = func(handler gin.HandlerFunc) gin.HandlerFunc
 
+// IWithInit 处理器的初始化接口，返回错误时启动失败
 type IWithInit interface {
+	Init() error
+}
+
+// 兼容旧版本没有返回值的 Init
+type iWithLegacyInit interface {
 	Init()
 }
 
+// IWithShutdown 服务停止时按注册顺序的逆序调用，用于关闭连接池、后台任务等资源
+type IWithShutdown interface {
+	Shutdown(ctx context.Context) error
+}
+
 type IWithMethod interface {
 	GetMethod() string
 }
@@ -16,15 +32,55 @@ type IWithSummary interface {
 	GetSummary() string
 }
 
+// IWithStatus 返回值实现该接口时使用返回的状态码（为 0 时忽略），
+// 处理器实现该接口时作为该接口默认的成功状态码
+type IWithStatus interface {
+	GetStatus() int
+}
+
+// IWithHeaders 返回值实现该接口时会写入返回的响应头
+type IWithHeaders interface {
+	GetHeaders() http.Header
+}
+
+// IWithBody 返回值实现该接口时序列化 GetBody 的结果而不是返回值本身
+type IWithBody interface {
+	GetBody() interface{}
+}
+
+type IWithScope interface {
+	GetScope() Scope
+}
+
+// IWithFactory 请求级作用域的处理器可以实现该接口自定义实例的创建方式，
+// 返回值必须与处理器类型一致
+type IWithFactory interface {
+	NewInstance() interface{}
+}
+
 type IError interface {
 	error
 	GetCode() int
 	GetMessage() string
 }
 
+// IWithHTTPStatus IError 实现该接口时使用返回的 HTTP 状态码（为 0 时忽略）
+type IWithHTTPStatus interface {
+	HTTPStatus() int
+}
+
+// IWithErrors 处理器实现该接口声明可能返回的错误，用于生成文档
+type IWithErrors interface {
+	GetErrors() []IError
+}
+
 type Error struct {
 	Code    int
 	Message string
+	// Status HTTP 状态码，为 0 时根据 Code 在 RegisterStatusCode 注册的映射中查找
+	Status int
+	// Args 错误目录中消息模板的参数
+	Args []interface{}
 }
 
 func NewError(code int, message string) IError {
@@ -45,3 +101,11 @@ func (e *Error) GetCode() int {
 func (e *Error) GetMessage() string {
 	return e.Message
 }
+
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *Error) GetArgs() []interface{} {
+	return e.Args
+}
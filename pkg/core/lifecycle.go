@@ -0,0 +1,78 @@
+package iz2go
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout 优雅停止时等待进行中请求的默认超时时间
+const DefaultShutdownTimeout = 10 * time.Second
+
+// RegisterShutdownHook 向 DefaultRegistry() 注册服务停止时调用的钩子，按注册顺序的逆序调用
+func RegisterShutdownHook(hook func(ctx context.Context) error) {
+	defaultRegistry.RegisterShutdownHook(hook)
+}
+
+// RunShutdownHooks 调用 DefaultRegistry() 中的所有停止钩子，返回合并后的错误
+func RunShutdownHooks(ctx context.Context) error {
+	return defaultRegistry.RunShutdownHooks(ctx)
+}
+
+// Run 启动 HTTP 服务，收到 SIGINT/SIGTERM 后停止接收新请求，
+// 在 ShutdownTimeout 内等待进行中的请求完成，最后调用停止钩子，停止钩子同样有 ShutdownTimeout 的时间
+func (e *Engine) Run(addr ...string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:    resolveAddress(addr),
+		Handler: e.Engine.Handler(),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+	}
+	stop()
+
+	timeout := e.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = server.Shutdown(shutdownCtx)
+		cancel()
+	}
+
+	// 停止钩子使用单独的超时时间，等待请求耗尽 ShutdownTimeout 时仍然可以关闭连接池等资源
+	hookCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return errors.Join(err, e.getRegistry().RunShutdownHooks(hookCtx))
+}
+
+// 与 gin 的 Run 保持一致：默认使用 PORT 环境变量，否则监听 :8080
+func resolveAddress(addr []string) string {
+	switch len(addr) {
+	case 0:
+		if port := os.Getenv("PORT"); port != "" {
+			return ":" + port
+		}
+		return ":8080"
+	case 1:
+		return addr[0]
+	default:
+		panic("too many parameters")
+	}
+}
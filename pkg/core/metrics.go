@@ -0,0 +1,130 @@
+package iz2go
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 请求上下文中保存请求参数绑定错误的键
+const bindErrorContextKey = "iz2go.bind_error"
+
+// MetricsConfig Prometheus 指标的配置
+type MetricsConfig struct {
+	// Path 暴露指标的路径，默认为 /metrics
+	Path string
+	// Namespace 指标名称的前缀，默认为 iz2go
+	Namespace string
+	// Registerer 注册指标的位置，默认为每个 Engine 单独创建的 prometheus.Registry（包含 Go 运行时和进程指标）
+	Registerer prometheus.Registerer
+	// Gatherer 暴露指标时读取的位置，默认为 Registerer（Registerer 不是 Gatherer 时为 prometheus.DefaultGatherer）
+	Gatherer prometheus.Gatherer
+	// Buckets 请求耗时直方图的分桶，默认为 prometheus.DefBuckets
+	Buckets []float64
+}
+
+type metrics struct {
+	requests        *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	bindingFailures *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+}
+
+// UseMetrics 为所有路由统计请求数、耗时、进行中的请求数、参数绑定失败次数和错误码，
+// 标签中的 route 为注册时的路由路径而不是实际的 URL，并在 config.Path 暴露指标。可以在 Build 之后调用
+func (e *Engine) UseMetrics(config MetricsConfig) {
+	if config.Path == "" {
+		config.Path = "/metrics"
+	}
+	if config.Namespace == "" {
+		config.Namespace = "iz2go"
+	}
+	if config.Registerer == nil {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		config.Registerer = registry
+	}
+	if config.Gatherer == nil {
+		if gatherer, ok := config.Registerer.(prometheus.Gatherer); ok {
+			config.Gatherer = gatherer
+		} else {
+			config.Gatherer = prometheus.DefaultGatherer
+		}
+	}
+	if config.Buckets == nil {
+		config.Buckets = prometheus.DefBuckets
+	}
+
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by route, method and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   config.Buckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served by route and method.",
+		}, []string{"method", "route"}),
+		bindingFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: "http",
+			Name:      "binding_failures_total",
+			Help:      "Number of requests whose parameters could not be fully bound.",
+		}, []string{"method", "route"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: "http",
+			Name:      "errors_total",
+			Help:      "Number of IError responses by route, method and error code.",
+		}, []string{"method", "route", "code"}),
+	}
+	config.Registerer.MustRegister(m.requests, m.duration, m.inFlight, m.bindingFailures, m.errors)
+	e.metrics = m
+	e.GET(config.Path, gin.WrapH(promhttp.HandlerFor(config.Gatherer, promhttp.HandlerOpts{})))
+}
+
+func (e *Engine) getMetrics() *metrics {
+	if e == nil {
+		return nil
+	}
+	return e.metrics
+}
+
+// measureRoute 统计路由的指标后调用 next，没有启用指标时直接调用 next
+func measureRoute(c *gin.Context, route *HandlerInfo, next gin.HandlerFunc) {
+	m := engineFromContext(c).getMetrics()
+	if m == nil {
+		next(c)
+		return
+	}
+	inFlight := m.inFlight.WithLabelValues(route.Method, route.Path)
+	inFlight.Inc()
+	defer inFlight.Dec()
+	start := time.Now()
+
+	next(c)
+
+	m.duration.WithLabelValues(route.Method, route.Path).Observe(time.Since(start).Seconds())
+	m.requests.WithLabelValues(route.Method, route.Path, strconv.Itoa(c.Writer.Status())).Inc()
+	if _, ok := c.Get(bindErrorContextKey); ok {
+		m.bindingFailures.WithLabelValues(route.Method, route.Path).Inc()
+	}
+	if err := ErrorFromContext(c); err != nil {
+		m.errors.WithLabelValues(route.Method, route.Path, strconv.Itoa(err.GetCode())).Inc()
+	}
+}
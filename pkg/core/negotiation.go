@@ -0,0 +1,264 @@
+package iz2go
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MIMEJSON     = binding.MIMEJSON
+	MIMEXML      = binding.MIMEXML
+	MIMEYAML     = binding.MIMEYAML
+	MIMEMSGPACK  = "application/x-msgpack"
+	MIMEPROTOBUF = binding.MIMEPROTOBUF
+)
+
+// 请求上下文中保存协商结果的键
+const formatContextKey = "iz2go.format"
+
+// 同一种格式的其他常见 MIME 类型，Accept 中出现这些类型时同样可以匹配
+var mimeAliases = map[string][]string{
+	MIMEXML:     {binding.MIMEXML2},
+	MIMEYAML:    {"application/yaml", "text/yaml"},
+	MIMEMSGPACK: {"application/msgpack"},
+}
+
+// IWithProduces 处理器实现该接口指定支持的响应格式，按优先级排序
+type IWithProduces interface {
+	GetProduces() []string
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// ParseProduces 获取处理器支持的响应格式，默认只有 JSON，XML、YAML、msgpack 和 protobuf 需要通过 IWithProduces 声明。
+// 只有声明了格式的处理器会根据 Accept 协商，默认的处理器总是返回 JSON，不会响应 406。
+// 无法渲染的格式会被去掉：响应类型没有实现 proto.Message 时的 protobuf，使用 nomsgpack 构建标签时的 msgpack。
+// 流式、文件、重定向和无内容的响应不参与协商，返回 nil
+func ParseProduces(handler interface{}, responseType reflect.Type) []string {
+	if h, ok := handler.(IWithProduces); ok {
+		if produces := h.GetProduces(); len(produces) > 0 {
+			return slices.DeleteFunc(slices.Clone(produces), func(mime string) bool {
+				return !canRender(mime, responseType)
+			})
+		}
+	}
+	if !isNegotiable(responseType) {
+		return nil
+	}
+	return []string{MIMEJSON}
+}
+
+func canRender(mime string, responseType reflect.Type) bool {
+	switch mime {
+	case MIMEMSGPACK:
+		return msgpackEnabled
+	case MIMEPROTOBUF:
+		return responseType != nil && getBodyType(responseType).Implements(protoMessageType)
+	}
+	return true
+}
+
+func isNegotiable(responseType reflect.Type) bool {
+	if responseType == nil {
+		return true
+	}
+	if _, ok := streamElemType(responseType); ok {
+		return false
+	}
+	return !isBinaryResponse(responseType) && !isRedirectResponse(responseType) && responseType != noContentType
+}
+
+// negotiateFormat 根据 Accept 从 offered 中选择权重最高的格式，权重相同时按 offered 的顺序，
+// 没有 Accept 时返回 offered 的第一个，都不匹配时返回空字符串
+func negotiateFormat(accept string, offered []string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return offered[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		q := 0.0
+		for _, mime := range append([]string{offer}, mimeAliases[offer]...) {
+			if mq := acceptQuality(ranges, mime); mq > q {
+				q = mq
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type acceptRange struct {
+	mime string
+	q    float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mime, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mime: strings.ToLower(strings.TrimSpace(mime)), q: q})
+	}
+	return ranges
+}
+
+// acceptQuality 返回最具体的匹配范围的权重：type/subtype 优先于 type/*，type/* 优先于 */*
+func acceptQuality(ranges []acceptRange, mime string) float64 {
+	mainType, _, _ := strings.Cut(mime, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mime == mime:
+			s = 2
+		case r.mime == mainType+"/*":
+			s = 1
+		case r.mime == "*/*" || r.mime == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiate 协商响应格式并保存到请求上下文，返回 false 时已经写出 406 错误。produces 为 nil 时不协商
+func negotiate(c *gin.Context, produces []string) bool {
+	if produces == nil {
+		return true
+	}
+	if engineFromContext(c).getEnvelope() != nil && slices.Contains(produces, MIMEPROTOBUF) {
+		// 包装后的响应不再是 proto.Message
+		produces = slices.DeleteFunc(slices.Clone(produces), func(mime string) bool {
+			return mime == MIMEPROTOBUF
+		})
+	}
+	format := negotiateFormat(c.GetHeader("Accept"), produces)
+	if format == "" {
+		OnError(c, NewHTTPError(http.StatusNotAcceptable, http.StatusNotAcceptable,
+			"supported formats: "+strings.Join(produces, ", ")))
+		return false
+	}
+	if len(produces) > 1 {
+		c.Writer.Header().Add("Vary", "Accept")
+	}
+	c.Set(formatContextKey, format)
+	return true
+}
+
+// renderNegotiated 以协商得到的格式写出响应，没有经过协商时使用 JSON
+func renderNegotiated(c *gin.Context, status int, response interface{}) {
+	data, ok := encodeNegotiated(c, response)
+	if ok {
+		c.Render(status, data)
+	}
+}
+
+// encodeNegotiated 以协商得到的格式序列化响应。失败时交给 OnError 响应 500 并返回 false，
+// 而不是写出状态码 200 和空的响应体
+func encodeNegotiated(c *gin.Context, response interface{}) (render.Data, bool) {
+	format := c.GetString(formatContextKey)
+	r, err := negotiatedRender(format, response)
+	writer := &bufferedWriter{ResponseWriter: c.Writer}
+	if err == nil {
+		err = r.Render(writer)
+	}
+	if err != nil {
+		_ = c.Error(err)
+		OnError(c, engineFromContext(c).getRegistry().ToIError(fmt.Errorf("render %s response: %w", cmp.Or(format, MIMEJSON), err)))
+		return render.Data{}, false
+	}
+	return render.Data{ContentType: writer.Header().Get("Content-Type"), Data: writer.body.Bytes()}, true
+}
+
+func negotiatedRender(format string, response interface{}) (render.Render, error) {
+	switch format {
+	case MIMEXML:
+		return render.XML{Data: response}, nil
+	case MIMEYAML:
+		// 经过 JSON 转换，使 YAML 的字段名与 json 标签和接口文档一致
+		value, err := toJSONValue(response)
+		if err != nil {
+			return nil, err
+		}
+		return render.YAML{Data: value}, nil
+	case MIMEMSGPACK:
+		return msgpackRender(response)
+	case MIMEPROTOBUF:
+		message, ok := response.(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("%T does not implement proto.Message", response)
+		}
+		return render.ProtoBuf{Data: message}, nil
+	}
+	return render.JSON{Data: response}, nil
+}
+
+// toJSONValue 将响应转换为 JSON 解码后的 map、slice 和基本类型，整数保持为 int64 而不是 float64
+func toJSONValue(response interface{}) (interface{}, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertNumbers(value), nil
+}
+
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
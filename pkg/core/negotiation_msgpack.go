@@ -0,0 +1,11 @@
+//go:build !nomsgpack
+
+package iz2go
+
+import "github.com/gin-gonic/gin/render"
+
+const msgpackEnabled = true
+
+func msgpackRender(response interface{}) (render.Render, error) {
+	return render.MsgPack{Data: response}, nil
+}
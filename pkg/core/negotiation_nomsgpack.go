@@ -0,0 +1,16 @@
+//go:build nomsgpack
+
+package iz2go
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// 与 gin 一致，使用 nomsgpack 构建标签时不支持 msgpack，ParseProduces 会去掉 msgpack
+const msgpackEnabled = false
+
+func msgpackRender(response interface{}) (render.Render, error) {
+	return nil, errors.New("msgpack is disabled by the nomsgpack build tag")
+}
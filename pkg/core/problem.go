@@ -0,0 +1,197 @@
+package iz2go
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType RFC 7807 错误响应的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// ErrorRenderer 所有错误钩子都没有中断时用于写出错误响应
+type ErrorRenderer interface {
+	Render(c *gin.Context, status int, err IError)
+	// Definition 错误响应在接口文档中的定义
+	Definition() Definition
+	// ContentType 错误响应的 Content-Type，用于接口文档
+	ContentType() string
+}
+
+// JSONErrorRenderer 默认的错误渲染，响应为 {"code","message"}
+type JSONErrorRenderer struct{}
+
+func (JSONErrorRenderer) Render(c *gin.Context, status int, err IError) {
+	c.JSON(status, gin.H{"code": err.GetCode(), "message": err.GetMessage()})
+}
+
+func (JSONErrorRenderer) Definition() Definition {
+	return Definition{
+		Type: "object",
+		Properties: map[string]Property{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+		},
+	}
+}
+
+func (JSONErrorRenderer) ContentType() string {
+	return gin.MIMEJSON
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// IWithFieldErrors IError 实现该接口时会在 Problem Details 中输出 errors 列表
+type IWithFieldErrors interface {
+	GetFieldErrors() []FieldError
+}
+
+// IWithProblemType IError 实现该接口时作为 Problem Details 的 type 和 title
+type IWithProblemType interface {
+	GetProblemType() (typ string, title string)
+}
+
+// IWithExtensions IError 实现该接口时返回的字段会合并到 Problem Details 中
+type IWithExtensions interface {
+	GetExtensions() map[string]interface{}
+}
+
+// ValidationError 参数校验错误，HTTP 状态码为 400
+type ValidationError struct {
+	Message string
+	Fields  []FieldError
+}
+
+func NewValidationError(message string, fields ...FieldError) IError {
+	return &ValidationError{
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) GetCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *ValidationError) GetMessage() string {
+	return e.Message
+}
+
+func (e *ValidationError) HTTPStatus() int {
+	return http.StatusBadRequest
+}
+
+func (e *ValidationError) GetFieldErrors() []FieldError {
+	return e.Fields
+}
+
+// ProblemDetails RFC 7807 错误响应，code 作为扩展字段输出业务错误码
+type ProblemDetails struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Status     int                    `json:"status"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Code       int                    `json:"code"`
+	Errors     []FieldError           `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON 将 Extensions 展开到顶层，不覆盖标准字段
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	type problem ProblemDetails
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+	fields := make(map[string]interface{}, len(p.Extensions)+7)
+	for key, value := range p.Extensions {
+		fields[key] = value
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// NewProblemDetails 根据 IError 构建 Problem Details
+func NewProblemDetails(c *gin.Context, status int, err IError) ProblemDetails {
+	problem := ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.GetMessage(),
+		Code:   err.GetCode(),
+	}
+	if c != nil && c.Request != nil {
+		problem.Instance = c.Request.URL.Path
+	}
+	var problemType IWithProblemType
+	if errors.As(err, &problemType) {
+		if typ, title := problemType.GetProblemType(); typ != "" {
+			problem.Type = typ
+			problem.Title = title
+		}
+	}
+	var fieldErrors IWithFieldErrors
+	if errors.As(err, &fieldErrors) {
+		problem.Errors = fieldErrors.GetFieldErrors()
+	}
+	var extensions IWithExtensions
+	if errors.As(err, &extensions) {
+		problem.Extensions = extensions.GetExtensions()
+	}
+	return problem
+}
+
+// ProblemDetailsRenderer 以 application/problem+json 输出 RFC 7807 错误响应
+type ProblemDetailsRenderer struct{}
+
+func (ProblemDetailsRenderer) Render(c *gin.Context, status int, err IError) {
+	data, marshalErr := json.Marshal(NewProblemDetails(c, status, err))
+	if marshalErr != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, ProblemContentType, data)
+}
+
+func (ProblemDetailsRenderer) Definition() Definition {
+	return Definition{
+		Type: "object",
+		Properties: map[string]Property{
+			"type":     {Type: "string", Description: "URI reference that identifies the problem type"},
+			"title":    {Type: "string", Description: "short, human-readable summary of the problem type"},
+			"status":   {Type: "integer", Description: "HTTP status code"},
+			"detail":   {Type: "string", Description: "human-readable explanation specific to this occurrence"},
+			"instance": {Type: "string", Description: "URI reference that identifies the specific occurrence"},
+			"code":     {Type: "integer", Description: "application error code"},
+			"errors": {
+				Type:        "array",
+				Description: "validation errors",
+				Items: &Schema{
+					Type: "object",
+					Properties: map[string]Property{
+						"field":   {Type: "string"},
+						"message": {Type: "string"},
+					},
+				},
+			},
+		},
+		Required: []string{"type", "title", "status"},
+	}
+}
+
+func (ProblemDetailsRenderer) ContentType() string {
+	return ProblemContentType
+}
@@ -0,0 +1,65 @@
+package iz2go
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PanicError 处理器 panic 时转换得到的内部错误，Value 和 Stack 只用于日志，
+// 返回给客户端的 Message 不包含 panic 的具体内容
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) GetCode() int {
+	return http.StatusInternalServerError
+}
+
+func (e *PanicError) GetMessage() string {
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+func (e *PanicError) HTTPStatus() int {
+	return http.StatusInternalServerError
+}
+
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverHandler 捕获处理器中的 panic，记录日志后交给错误钩子处理
+func recoverHandler(c *gin.Context, handlerName string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	// 与 net/http 约定一致，ErrAbortHandler 用于主动中断响应，不做处理
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+	err := &PanicError{Value: r, Stack: debug.Stack()}
+	log.Printf("[iz2go] panic recovered: %s %s (%s): %v\n%s",
+		c.Request.Method, c.FullPath(), handlerName, r, err.Stack)
+	OnError(c, err)
+}
+
+// logError 记录内部错误及其原始原因
+func logError(c *gin.Context, handlerName string, err IError) {
+	cause := error(err)
+	if wrapped, ok := err.(*WrappedError); ok {
+		cause = wrapped.Cause
+	}
+	log.Printf("[iz2go] internal error: %s %s (%s): %v", c.Request.Method, c.FullPath(), handlerName, cause)
+}
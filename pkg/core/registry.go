@@ -0,0 +1,235 @@
+package iz2go
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Registry 保存路由、各类钩子、错误映射和错误目录。包级别的 RegisterRoute、RegisterErrorHook、RegisterStatusCode 等函数使用 DefaultRegistry()，
+// 同一进程中需要多个互不影响的 Engine 时（例如公开 API 和管理 API、并行的测试）为每个 Engine 使用单独的 Registry
+type Registry struct {
+	mu sync.RWMutex
+	// routes 的键为请求方法和路径，例如 "GET /users/:id"
+	routes map[string]*HandlerInfo
+
+	errorHooks   hookChain[func(ctx *gin.Context, err IError) (IError, bool)]
+	successHooks hookChain[successHook]
+	beforeHooks  hookChain[beforeHook]
+
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(ctx context.Context) error
+
+	errorsMu      sync.RWMutex
+	statusCodes   map[int]int
+	errorMappings []func(err error) (IError, bool)
+	catalog       *Catalog
+}
+
+type beforeHook = func(ctx *gin.Context, route *HandlerInfo, request interface{}) IError
+
+var defaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		routes:      map[string]*HandlerInfo{},
+		statusCodes: map[int]int{},
+		catalog:     NewCatalog(""),
+	}
+}
+
+// DefaultRegistry 返回包级别函数使用的 Registry，Default() 创建的 Engine 使用它
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// RegisterRoute 注册路由，同一请求方法下路径重复或与已有路由在 gin 中冲突时返回 *RouteConflictError。
+// 处理器的停止钩子会在 Engine 停止时调用
+func (r *Registry) RegisterRoute(path string, handler *HandlerInfo) error {
+	r.mu.Lock()
+	for _, existing := range r.routes {
+		if existing.Method != handler.Method {
+			continue
+		}
+		if reason := ConflictReason(path, existing.Path); reason != "" {
+			r.mu.Unlock()
+			return &RouteConflictError{
+				Method:         handler.Method,
+				Path:           path,
+				Source:         handler.Source,
+				ExistingPath:   existing.Path,
+				ExistingSource: existing.Source,
+				Reason:         reason,
+			}
+		}
+	}
+	// 保存副本，同一个 HandlerInfo 可以注册到多个路径或多个 Registry
+	route := *handler
+	route.Path = path
+	r.routes[routeKey(route.Method, path)] = &route
+	r.mu.Unlock()
+	r.RegisterShutdownHook(handler.Shutdown)
+	return nil
+}
+
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
+
+// Routes 返回已注册的路由，按路径和请求方法排序
+func (r *Registry) Routes() []*HandlerInfo {
+	r.mu.RLock()
+	routes := slices.Collect(maps.Values(r.routes))
+	r.mu.RUnlock()
+	slices.SortFunc(routes, func(a, b *HandlerInfo) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+func (r *Registry) RegisterErrorHook(hook func(ctx *gin.Context, err IError) (IError, bool), opts ...HookOption) {
+	r.errorHooks.add(hook, opts)
+}
+
+func (r *Registry) RemoveErrorHook(name string) bool {
+	return r.errorHooks.remove(name)
+}
+
+func (r *Registry) RegisterBeforeHook(hook beforeHook, opts ...HookOption) {
+	r.beforeHooks.add(hook, opts)
+}
+
+func (r *Registry) RemoveBeforeHook(name string) bool {
+	return r.beforeHooks.remove(name)
+}
+
+func (r *Registry) RemoveSuccessHook(name string) bool {
+	return r.successHooks.remove(name)
+}
+
+// RegisterSuccessHookTo 向指定的 Registry 注册成功钩子，规则同 RegisterSuccessHook
+func RegisterSuccessHookTo[T any](registry *Registry, hook func(ctx *gin.Context, response T) (T, bool), opts ...HookOption) {
+	registry.successHooks.add(newSuccessHook(hook), opts)
+}
+
+// RegisterShutdownHook 注册服务停止时调用的钩子，按注册顺序的逆序调用
+func (r *Registry) RegisterShutdownHook(hook func(ctx context.Context) error) {
+	if hook == nil {
+		return
+	}
+	r.shutdownMu.Lock()
+	defer r.shutdownMu.Unlock()
+	r.shutdownHooks = append(r.shutdownHooks, hook)
+}
+
+// RunShutdownHooks 按注册顺序的逆序调用所有停止钩子，返回合并后的错误
+func (r *Registry) RunShutdownHooks(ctx context.Context) error {
+	r.shutdownMu.Lock()
+	hooks := r.shutdownHooks
+	r.shutdownHooks = nil
+	r.shutdownMu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterStatusCode 注册业务错误码对应的 HTTP 状态码，错误没有通过 HTTPStatus 指定状态码时使用
+func (r *Registry) RegisterStatusCode(code int, status int) {
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	r.statusCodes[code] = status
+}
+
+// HTTPStatus 获取错误对应的 HTTP 状态码：优先使用 HTTPStatus，
+// 其次是 RegisterStatusCode 注册的映射，都没有时为 500
+func (r *Registry) HTTPStatus(err IError) int {
+	if e, ok := err.(IWithHTTPStatus); ok {
+		if status := e.HTTPStatus(); status != 0 {
+			return status
+		}
+	}
+	r.errorsMu.RLock()
+	status, ok := r.statusCodes[err.GetCode()]
+	r.errorsMu.RUnlock()
+	if ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// RegisterErrorMapping 注册哨兵错误到 IError 的映射，规则同包级别的 RegisterErrorMapping
+func (r *Registry) RegisterErrorMapping(target error, to IError) {
+	r.registerErrorMapping(func(err error) (IError, bool) {
+		if errors.Is(err, target) {
+			return to, true
+		}
+		return nil, false
+	})
+}
+
+// RegisterErrorTypeTo 向指定的 Registry 注册错误类型到 IError 的映射，规则同 RegisterErrorType
+func RegisterErrorTypeTo[E error](registry *Registry, mapper func(err E) IError) {
+	registry.registerErrorMapping(func(err error) (IError, bool) {
+		var target E
+		if errors.As(err, &target) {
+			return mapper(target), true
+		}
+		return nil, false
+	})
+}
+
+func (r *Registry) registerErrorMapping(mapping func(err error) (IError, bool)) {
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	r.errorMappings = append(r.errorMappings, mapping)
+}
+
+// ToIError 将 error 转换为 IError，规则同包级别的 ToIError，使用该 Registry 注册的映射
+func (r *Registry) ToIError(err error) IError {
+	if err == nil {
+		return nil
+	}
+	if ierr, ok := err.(IError); ok {
+		return ierr
+	}
+	var ierr IError
+	if errors.As(err, &ierr) {
+		return &WrappedError{IError: ierr, Cause: err, registry: r}
+	}
+
+	r.errorsMu.RLock()
+	mappings := r.errorMappings
+	r.errorsMu.RUnlock()
+	for _, mapping := range mappings {
+		if ierr, ok := mapping(err); ok && ierr != nil {
+			return &WrappedError{IError: ierr, Cause: err, registry: r}
+		}
+	}
+	return &WrappedError{IError: InternalError(http.StatusText(http.StatusInternalServerError)), Cause: err, registry: r}
+}
+
+// Catalog 返回该 Registry 的错误目录，未通过 SetCatalog 指定错误目录的 Engine 使用它
+func (r *Registry) Catalog() *Catalog {
+	return r.catalog
+}
+
+// NewCatalogError 创建使用该 Registry 错误目录消息的错误，规则同包级别的 NewCatalogError
+func (r *Registry) NewCatalogError(code int, args ...interface{}) IError {
+	err := &Error{Code: code, Args: args}
+	if entry, ok := r.catalog.Lookup(code); ok {
+		err.Status = entry.Status
+	}
+	err.Message, _ = r.catalog.Message(code, "", args...)
+	return err
+}
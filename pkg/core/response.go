@@ -0,0 +1,302 @@
+package iz2go
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// File 返回本地文件，支持 Range 和 If-Modified-Since 请求
+type File struct {
+	Path string
+	// Name 下载时的文件名，为空时使用 Path 中的文件名
+	Name string
+	// ContentType 为空时根据文件扩展名推断
+	ContentType string
+	// Inline 为 true 时浏览器直接展示而不是下载
+	Inline bool
+}
+
+// Stream 从 io.Reader 读取响应内容，Reader 实现 io.Closer 时会在写完后关闭；
+// Reader 实现 io.ReadSeeker 时支持 Range 请求
+type Stream struct {
+	Reader      io.Reader
+	ContentType string
+	// Length 内容长度，小于等于 0 时不设置 Content-Length
+	Length int64
+	// Name 下载时的文件名，为空时不设置 Content-Disposition
+	Name    string
+	Inline  bool
+	ModTime time.Time
+}
+
+// Redirect 重定向到 URL，Code 默认为 302
+type Redirect struct {
+	URL  string
+	Code int
+}
+
+var (
+	fileType     = reflect.TypeOf(File{})
+	streamType   = reflect.TypeOf(Stream{})
+	redirectType = reflect.TypeOf(Redirect{})
+)
+
+// isBinaryResponse 判断返回值类型是否为 File 或 Stream
+func isBinaryResponse(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == fileType || t == streamType
+}
+
+func isRedirectResponse(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == redirectType
+}
+
+// renderSpecialResponse 渲染 File、Stream 和 Redirect 类型的返回值，返回是否已处理
+func renderSpecialResponse(c *gin.Context, response interface{}) bool {
+	switch r := response.(type) {
+	case File:
+		renderFile(c, &r)
+	case *File:
+		renderFile(c, r)
+	case Stream:
+		renderStream(c, &r)
+	case *Stream:
+		renderStream(c, r)
+	case Redirect:
+		renderRedirect(c, &r)
+	case *Redirect:
+		renderRedirect(c, r)
+	default:
+		return false
+	}
+	return true
+}
+
+func renderFile(c *gin.Context, file *File) {
+	if file == nil {
+		OnError(c, NotFound(http.StatusText(http.StatusNotFound)))
+		return
+	}
+	f, err := os.Open(file.Path)
+	if err != nil {
+		OnError(c, NotFound(http.StatusText(http.StatusNotFound)))
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		OnError(c, NotFound(http.StatusText(http.StatusNotFound)))
+		return
+	}
+
+	name := file.Name
+	if name == "" {
+		name = filepath.Base(file.Path)
+	}
+	setContentDisposition(c, name, file.Inline)
+	if file.ContentType != "" {
+		c.Header("Content-Type", file.ContentType)
+	}
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+}
+
+func renderStream(c *gin.Context, stream *Stream) {
+	if stream == nil || stream.Reader == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if closer, ok := stream.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	contentType := stream.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(stream.Name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if stream.Name != "" {
+		setContentDisposition(c, stream.Name, stream.Inline)
+	}
+
+	if seeker, ok := stream.Reader.(io.ReadSeeker); ok {
+		c.Header("Content-Type", contentType)
+		http.ServeContent(c.Writer, c.Request, stream.Name, stream.ModTime, seeker)
+		return
+	}
+	if !stream.ModTime.IsZero() {
+		c.Header("Last-Modified", stream.ModTime.UTC().Format(http.TimeFormat))
+	}
+	length := stream.Length
+	if length <= 0 {
+		length = -1
+	}
+	c.DataFromReader(http.StatusOK, length, contentType, stream.Reader, nil)
+}
+
+func renderRedirect(c *gin.Context, redirect *Redirect) {
+	if redirect == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	code := redirect.Code
+	if code == 0 {
+		code = http.StatusFound
+	}
+	c.Redirect(code, redirect.URL)
+}
+
+func setContentDisposition(c *gin.Context, name string, inline bool) {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+}
+
+// Result 携带状态码和响应头的返回值，Body 会作为响应内容序列化
+type Result[T any] struct {
+	Status int
+	Header http.Header
+	Body   T
+}
+
+func (r Result[T]) GetStatus() int {
+	return r.Status
+}
+
+func (r Result[T]) GetHeaders() http.Header {
+	return r.Header
+}
+
+func (r Result[T]) GetBody() interface{} {
+	return r.Body
+}
+
+// Created 返回 201，Location 不为空时写入 Location 响应头
+type Created[T any] struct {
+	Location string
+	Header   http.Header
+	Body     T
+}
+
+func (r Created[T]) GetStatus() int {
+	return http.StatusCreated
+}
+
+func (r Created[T]) GetHeaders() http.Header {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if r.Location != "" {
+		header.Set("Location", r.Location)
+	}
+	return header
+}
+
+func (r Created[T]) GetBody() interface{} {
+	return r.Body
+}
+
+// NoContent 返回 204 且没有响应内容，Execute 只返回 IError 时等同于返回 NoContent
+type NoContent struct{}
+
+func (NoContent) GetStatus() int {
+	return http.StatusNoContent
+}
+
+var withBodyType = reflect.TypeOf((*IWithBody)(nil)).Elem()
+
+// ParseStatus 获取成功时的状态码：处理器实现 IWithStatus 时优先，
+// 其次是响应类型零值的 GetStatus，都没有时为 200
+func ParseStatus(handler interface{}, responseType reflect.Type) int {
+	if h, ok := handler.(IWithStatus); ok {
+		if status := h.GetStatus(); status != 0 {
+			return status
+		}
+	}
+	if responseType != nil && responseType.Kind() != reflect.Interface {
+		zero := reflect.New(responseType).Elem().Interface()
+		if r, ok := zero.(IWithStatus); ok && !isNilPointer(zero) {
+			if status := r.GetStatus(); status != 0 {
+				return status
+			}
+		}
+	}
+	return http.StatusOK
+}
+
+// getBodyType 获取响应实际序列化的类型，Result[T] 等包装类型返回 Body 字段的类型
+func getBodyType(responseType reflect.Type) reflect.Type {
+	t := responseType
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && responseType.Implements(withBodyType) {
+		if field, ok := t.FieldByName("Body"); ok {
+			return field.Type
+		}
+	}
+	return responseType
+}
+
+// resolveResponse 写入响应头并处理状态码和包装类型，返回实际的响应内容和状态码
+func resolveResponse(c *gin.Context, response interface{}, status int) (interface{}, int) {
+	if response == nil || isNilPointer(response) {
+		return response, status
+	}
+	if r, ok := response.(IWithHeaders); ok {
+		header := c.Writer.Header()
+		for key, values := range r.GetHeaders() {
+			for _, value := range values {
+				header.Add(key, value)
+			}
+		}
+	}
+	if r, ok := response.(IWithLastModified); ok {
+		if lastModified := r.GetLastModified(); !lastModified.IsZero() {
+			c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		}
+	}
+	if r, ok := response.(IWithStatus); ok {
+		if code := r.GetStatus(); code != 0 {
+			status = code
+		}
+	}
+	if r, ok := response.(IWithBody); ok {
+		response = r.GetBody()
+	}
+	return response, status
+}
+
+func isNilPointer(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// 与 net/http 一致：1xx、204 和 304 不允许有响应内容
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
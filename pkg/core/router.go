@@ -1,15 +1,102 @@
 package iz2go
 
 import (
-	"html/template"
+	"log/slog"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-var routes = map[string]*HandlerInfo{}
+// 请求上下文中保存当前 Engine、路由和错误的键
+const (
+	engineContextKey = "iz2go.engine"
+	routeContextKey  = "iz2go.route"
+	errorContextKey  = "iz2go.error"
+)
 
 type Engine struct {
 	*gin.Engine
+	// ShutdownTimeout 优雅停止时等待进行中请求的超时时间，默认为 DefaultShutdownTimeout
+	ShutdownTimeout time.Duration
+
+	registry      *Registry
+	errorRenderer ErrorRenderer
+	catalog       *Catalog
+	envelope      *Envelope
+	tracing       *tracing
+	metrics       *metrics
+	accessLogger  *slog.Logger
+	audit         *AuditConfig
+}
+
+// Registry 返回 Engine 使用的路由和钩子
+func (e *Engine) Registry() *Registry {
+	return e.getRegistry()
+}
+
+func (e *Engine) getRegistry() *Registry {
+	if e == nil || e.registry == nil {
+		return defaultRegistry
+	}
+	return e.registry
+}
+
+// SetErrorRenderer 设置错误钩子都没有中断时使用的错误渲染方式，需要在 RenderSwagger 之前调用
+func (e *Engine) SetErrorRenderer(renderer ErrorRenderer) {
+	e.errorRenderer = renderer
+}
+
+// UseProblemDetails 使用 RFC 7807 application/problem+json 格式输出错误
+func (e *Engine) UseProblemDetails() {
+	e.SetErrorRenderer(ProblemDetailsRenderer{})
+}
+
+func (e *Engine) getErrorRenderer() ErrorRenderer {
+	if e == nil {
+		return JSONErrorRenderer{}
+	}
+	if e.errorRenderer != nil {
+		return e.errorRenderer
+	}
+	if e.envelope != nil {
+		return envelopeErrorRenderer{envelope: e.envelope}
+	}
+	return JSONErrorRenderer{}
+}
+
+// SetEnvelope 设置统一的响应包装，成功和错误响应都会被包装，接口文档也会随之改变；
+// 通过 SetErrorRenderer 指定了错误渲染方式时错误响应不会被包装。需要在 RenderSwagger 之前调用
+func (e *Engine) SetEnvelope(envelope *Envelope) {
+	e.envelope = envelope
+}
+
+func (e *Engine) getEnvelope() *Envelope {
+	if e == nil {
+		return nil
+	}
+	return e.envelope
+}
+
+// SetCatalog 设置用于本地化错误消息的错误目录，默认为 Engine 的 Registry 的错误目录
+func (e *Engine) SetCatalog(catalog *Catalog) {
+	e.catalog = catalog
+}
+
+func (e *Engine) getCatalog() *Catalog {
+	if e == nil || e.catalog == nil {
+		return e.getRegistry().Catalog()
+	}
+	return e.catalog
+}
+
+// engineFromContext 获取处理当前请求的 Engine，不是由 Engine 处理的请求返回 nil
+func engineFromContext(c *gin.Context) *Engine {
+	if value, ok := c.Get(engineContextKey); ok {
+		if engine, ok := value.(*Engine); ok {
+			return engine
+		}
+	}
+	return nil
 }
 
 type SwaggerRenderConfig struct {
@@ -38,7 +125,7 @@ func (e *Engine) RenderSwagger(config *SwaggerRenderConfig) {
 		config.Info.Version = "1.0.0"
 	}
 
-	swaggerConfig := GenerateSwagger(config.Info)
+	swaggerConfig := generateSwagger(config.Info, e)
 	e.GET(config.OpenApiPath, func(c *gin.Context) {
 		c.JSON(200, swaggerConfig)
 	})
@@ -51,18 +138,45 @@ func (e *Engine) RenderSwagger(config *SwaggerRenderConfig) {
 	})
 }
 
+// Default 使用 DefaultRegistry() 中的路由和钩子创建 Engine
 func Default() *Engine {
-	router := gin.Default()
-	tmpl := template.Must(template.New("swagger").Parse(swaggerHTML))
-	router.SetHTMLTemplate(tmpl)
-	for path, route := range routes {
-		router.Handle(route.Method, path, route.Handler)
+	return NewBuilder().WithRegistry(defaultRegistry).Build()
+}
+
+// routeWrappers 依次包裹路由的处理器，没有启用的功能直接调用 next
+var routeWrappers = []func(c *gin.Context, route *HandlerInfo, next gin.HandlerFunc){
+	logRoute,
+	auditRoute,
+	measureRoute,
+	traceRoute,
+}
+
+// withRoute 将路由信息保存到请求上下文后调用处理器
+func withRoute(route *HandlerInfo) gin.HandlerFunc {
+	handler := route.Handler
+	for i := len(routeWrappers) - 1; i >= 0; i-- {
+		wrap, next := routeWrappers[i], handler
+		handler = func(c *gin.Context) {
+			wrap(c, route, next)
+		}
+	}
+	return func(c *gin.Context) {
+		c.Set(routeContextKey, route)
+		handler(c)
 	}
-	return &Engine{
-		Engine: router,
+}
+
+// RouteFromContext 获取当前请求匹配的路由，不是通过 RegisterRoute 注册的路由返回 nil
+func RouteFromContext(c *gin.Context) *HandlerInfo {
+	if value, ok := c.Get(routeContextKey); ok {
+		if route, ok := value.(*HandlerInfo); ok {
+			return route
+		}
 	}
+	return nil
 }
 
-func RegisterRoute(path string, handler *HandlerInfo) {
-	routes[path] = handler
+// RegisterRoute 向 DefaultRegistry() 注册路由，冲突时返回 *RouteConflictError
+func RegisterRoute(path string, handler *HandlerInfo) error {
+	return defaultRegistry.RegisterRoute(path, handler)
 }
@@ -0,0 +1,89 @@
+package iz2go
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Scope 表示处理器实例的作用域
+//
+// 并发约定：
+//   - ScopeSingleton（默认）：所有请求共享同一个处理器实例，Execute 可能被并发调用，
+//     处理器结构体上只能保存 Init 时初始化、之后只读（或自身并发安全）的依赖，
+//     不能在字段上保存单个请求的状态
+//   - ScopeRequest：每个请求都会从原型（已执行过 Init 的实例）浅拷贝出一个新实例，
+//     Init 时设置的依赖会被复制过去，请求内可以安全地读写结构体字段；
+//     注意指针、map、slice 等依赖仍然是共享的，它们自身需要保证并发安全
+type Scope int
+
+const (
+	ScopeSingleton Scope = iota
+	ScopeRequest
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeSingleton:
+		return "singleton"
+	case ScopeRequest:
+		return "request"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// RequestScoped 嵌入到处理器中即可声明为请求级作用域，用法同 *iz2go.Post
+type RequestScoped struct{}
+
+func (r *RequestScoped) GetScope() Scope {
+	return ScopeRequest
+}
+
+// Singleton 嵌入到处理器中显式声明为单例作用域
+type Singleton struct{}
+
+func (s *Singleton) GetScope() Scope {
+	return ScopeSingleton
+}
+
+func ParseScope(handler interface{}) Scope {
+	if h, ok := handler.(IWithScope); ok {
+		return h.GetScope()
+	}
+	return ScopeSingleton
+}
+
+// newInstanceProvider 根据作用域返回获取处理器实例的函数
+func newInstanceProvider(handler interface{}, scope Scope) func() reflect.Value {
+	prototype := reflect.ValueOf(handler)
+	if scope != ScopeRequest {
+		return func() reflect.Value {
+			return prototype
+		}
+	}
+
+	// 处理器实现了 IWithFactory 时由处理器自己负责创建实例
+	if factory, ok := handler.(IWithFactory); ok {
+		handlerType := prototype.Type()
+		return func() reflect.Value {
+			instance := reflect.ValueOf(factory.NewInstance())
+			if instance.Type() != handlerType {
+				panic(fmt.Sprintf("NewInstance must return %s, got %s", handlerType, instance.Type()))
+			}
+			return instance
+		}
+	}
+
+	// 值类型的处理器每次调用本身就是一份拷贝
+	if prototype.Kind() != reflect.Ptr || prototype.Elem().Kind() != reflect.Struct {
+		return func() reflect.Value {
+			return prototype
+		}
+	}
+
+	// 从原型浅拷贝，Init 时注入的依赖会一并复制
+	return func() reflect.Value {
+		instance := reflect.New(prototype.Type().Elem())
+		instance.Elem().Set(prototype.Elem())
+		return instance
+	}
+}
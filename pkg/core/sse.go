@@ -0,0 +1,169 @@
+package iz2go
+
+import (
+	"log"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultHeartbeat SSE 流默认的心跳间隔
+const DefaultHeartbeat = 15 * time.Second
+
+// Event 一个 Server-Sent Event，Execute 返回的 channel 或 iter.Seq 的元素为 Event 时
+// 会使用其中的事件名、ID 和重连时间，否则元素本身会作为 data 发送
+type Event struct {
+	Event string
+	ID    string
+	// Retry 客户端断线重连的等待时间
+	Retry time.Duration
+	Data  interface{}
+}
+
+// IWithHeartbeat 自定义 SSE 心跳间隔，返回值小于 0 时不发送心跳
+type IWithHeartbeat interface {
+	GetHeartbeat() time.Duration
+}
+
+func ParseHeartbeat(handler interface{}) time.Duration {
+	if h, ok := handler.(IWithHeartbeat); ok {
+		return h.GetHeartbeat()
+	}
+	return DefaultHeartbeat
+}
+
+var eventType = reflect.TypeOf(Event{})
+
+// streamElemType 判断返回值是否为 SSE 流（可接收的 channel 或 iter.Seq），并返回元素类型
+func streamElemType(t reflect.Type) (reflect.Type, bool) {
+	switch t.Kind() {
+	case reflect.Chan:
+		if t.ChanDir()&reflect.RecvDir != 0 {
+			return t.Elem(), true
+		}
+	case reflect.Func:
+		// iter.Seq[T] 即 func(yield func(T) bool)
+		if t.NumIn() != 1 || t.NumOut() != 0 {
+			return nil, false
+		}
+		yield := t.In(0)
+		if yield.Kind() == reflect.Func && yield.NumIn() == 1 &&
+			yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool {
+			return yield.In(0), true
+		}
+	}
+	return nil, false
+}
+
+// streamResponse 将 channel 或 iter.Seq 以 text/event-stream 的形式写出，
+// 客户端断开连接时停止读取 channel，iter.Seq 的 yield 返回 false
+func streamResponse(c *gin.Context, stream reflect.Value, heartbeat time.Duration) {
+	header := c.Writer.Header()
+	header.Set("Content-Type", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(200)
+	c.Writer.Flush()
+
+	if stream.IsNil() {
+		return
+	}
+
+	done := c.Request.Context().Done()
+	items := stream
+	if stream.Kind() == reflect.Func {
+		items = iterToChan(stream, done)
+	}
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: items},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(tick)},
+	}
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			if !ok {
+				return
+			}
+			c.Render(-1, toSSEvent(item))
+		case 1:
+			return
+		case 2:
+			// 注释行作为心跳，客户端会忽略
+			if _, err := c.Writer.WriteString(":\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// iterToChan 在单独的 goroutine 中执行 iter.Seq，done 关闭后 yield 返回 false
+func iterToChan(seq reflect.Value, done <-chan struct{}) reflect.Value {
+	elemType, _ := streamElemType(seq.Type())
+	items := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, elemType), 0)
+	doneValue := reflect.ValueOf(done)
+	yield := reflect.MakeFunc(seq.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		chosen, _, _ := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: items, Send: args[0]},
+			{Dir: reflect.SelectRecv, Chan: doneValue},
+		})
+		return []reflect.Value{reflect.ValueOf(chosen == 0)}
+	})
+	go func() {
+		defer items.Close()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[iz2go] panic recovered in event stream: %v\n%s", r, debug.Stack())
+			}
+		}()
+		seq.Call([]reflect.Value{yield})
+	}()
+	return items
+}
+
+func toSSEvent(item reflect.Value) sse.Event {
+	if item.Kind() == reflect.Interface {
+		if item.IsNil() {
+			return sse.Event{Data: ""}
+		}
+		item = item.Elem()
+	}
+	if item.Kind() == reflect.Ptr && item.Type().Elem() == eventType {
+		if item.IsNil() {
+			return sse.Event{Data: ""}
+		}
+		item = item.Elem()
+	}
+	if item.Type() != eventType {
+		return sse.Event{Data: item.Interface()}
+	}
+	event := item.Interface().(Event)
+	retry := uint(0)
+	if event.Retry > 0 {
+		retry = uint(event.Retry.Milliseconds())
+	}
+	data := event.Data
+	if data == nil {
+		data = ""
+	}
+	return sse.Event{
+		Event: event.Event,
+		Id:    event.ID,
+		Retry: retry,
+		Data:  data,
+	}
+}
@@ -1,9 +1,14 @@
 package iz2go
 
 import (
+	"fmt"
+	"net/http"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
@@ -40,6 +45,9 @@ const swaggerHTML = `<!DOCTYPE html>
 </body>
 </html>`
 
+// 默认错误响应的定义名称
+const errorDefinitionName = "Error"
+
 // SwaggerConfig 表示 Swagger 配置
 type SwaggerConfig struct {
 	Swagger     string                `json:"swagger"`
@@ -62,10 +70,12 @@ type PathItem struct {
 }
 
 type Operation struct {
-	Tags       []string            `json:"tags"`
-	Summary    string              `json:"summary"`
-	Parameters []Parameter         `json:"parameters"`
-	Responses  map[string]Response `json:"responses"`
+	Tags        []string            `json:"tags"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Produces    []string            `json:"produces,omitempty"`
+	Parameters  []Parameter         `json:"parameters"`
+	Responses   map[string]Response `json:"responses"`
 }
 
 type Parameter struct {
@@ -73,13 +83,20 @@ type Parameter struct {
 	In          string  `json:"in"`
 	Required    bool    `json:"required"`
 	Type        string  `json:"type"`
+	Format      string  `json:"format,omitempty"`
 	Description string  `json:"description"`
 	Schema      *Schema `json:"schema,omitempty"`
 }
 
 type Response struct {
-	Description string `json:"description"`
-	Schema      Schema `json:"schema"`
+	Description string                    `json:"description"`
+	Schema      Schema                    `json:"schema"`
+	Headers     map[string]ResponseHeader `json:"headers,omitempty"`
+}
+
+type ResponseHeader struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
 }
 
 type Schema struct {
@@ -93,8 +110,11 @@ type Schema struct {
 
 type Property struct {
 	Type        string              `json:"type"`
+	Format      string              `json:"format,omitempty"`
+	Ref         string              `json:"$ref,omitempty"`
 	Description string              `json:"description,omitempty"`
 	Enum        []string            `json:"enum,omitempty"`
+	WriteOnly   bool                `json:"writeOnly,omitempty"`
 	Properties  map[string]Property `json:"properties,omitempty"`
 	Items       *Schema             `json:"items,omitempty"`
 }
@@ -105,8 +125,15 @@ type Definition struct {
 	Required   []string            `json:"required,omitempty"`
 }
 
-// GenerateSwagger 生成 Swagger 配置
+// GenerateSwagger 为 DefaultRegistry() 中的路由生成 Swagger 配置
 func GenerateSwagger(info *Info) *SwaggerConfig {
+	return generateSwagger(info, nil)
+}
+
+// generateSwagger 按 Engine 的路由、错误渲染方式和响应包装生成文档，engine 为 nil 时使用默认配置
+func generateSwagger(info *Info, engine *Engine) *SwaggerConfig {
+	errorRenderer := engine.getErrorRenderer()
+	envelope := engine.getEnvelope()
 	config := &SwaggerConfig{
 		Swagger:     "2.0",
 		Info:        *info,
@@ -114,7 +141,8 @@ func GenerateSwagger(info *Info) *SwaggerConfig {
 		Definitions: make(map[string]Definition),
 	}
 
-	for path, handler := range routes {
+	for _, handler := range engine.getRegistry().Routes() {
+		path := handler.Path
 		if handler.ApiName == "" {
 			pathParts := strings.ReplaceAll(path, "/", "_")
 			handler.ApiName = pathParts
@@ -126,6 +154,18 @@ func GenerateSwagger(info *Info) *SwaggerConfig {
 
 		method := getMethodFromHandler(handler)
 
+		if handler.WebSocket {
+			operation, definitions := generateWebSocketOperation(handler)
+			operation.Tags = []string{handlerType.Name()}
+			for name, definition := range definitions {
+				config.Definitions[name] = definition
+			}
+			pathItem := config.Paths[path]
+			pathItem.Get = operation
+			config.Paths[path] = pathItem
+			continue
+		}
+
 		// 获取请求参数类型
 		requestType := getRequestType(handler)
 		if requestType != nil {
@@ -140,6 +180,27 @@ func GenerateSwagger(info *Info) *SwaggerConfig {
 			for name, definition := range responsesDefinitions {
 				config.Definitions[name] = definition
 			}
+			if envelope != nil {
+				for name, definition := range applyEnvelope(handler, envelope, responses) {
+					config.Definitions[name] = definition
+				}
+			}
+			errorResponses, errorDefinitions := generateErrorResponses(handler, errorRenderer, engine.getRegistry())
+			for code, response := range errorResponses {
+				responses[code] = response
+			}
+			for name, definition := range errorDefinitions {
+				config.Definitions[name] = definition
+			}
+
+			if handler.SparseFields && !hasParameter(parameters, FieldsParam) {
+				parameters = append(parameters, Parameter{
+					Name:        FieldsParam,
+					In:          FromQuery,
+					Type:        "string",
+					Description: "Comma-separated fields to include in the response, e.g. id,name,owner.email",
+				})
+			}
 
 			// 创建操作
 			operation := &Operation{
@@ -148,6 +209,19 @@ func GenerateSwagger(info *Info) *SwaggerConfig {
 				Parameters: parameters,
 				Responses:  responses,
 			}
+			if _, ok := streamElemType(handler.Response); ok {
+				operation.Produces = []string{sse.ContentType}
+			} else if isBinaryResponse(handler.Response) {
+				operation.Produces = []string{"application/octet-stream"}
+			} else if len(handler.Produces) > 0 {
+				operation.Produces = slices.Clone(handler.Produces)
+			}
+			if contentType := errorRenderer.ContentType(); contentType != gin.MIMEJSON && !slices.Contains(operation.Produces, contentType) {
+				if len(operation.Produces) == 0 {
+					operation.Produces = []string{gin.MIMEJSON}
+				}
+				operation.Produces = append(operation.Produces, contentType)
+			}
 
 			// 添加到路径
 			pathItem := config.Paths[path]
@@ -209,6 +283,9 @@ func generateParameters(requestType reflect.Type, handler *HandlerInfo) ([]Param
 				Type:        getSwaggerType(field.Type),
 				Description: field.Tag.Get("description"),
 			}
+			if IsSensitive(field) {
+				param.Format = "password"
+			}
 
 			// 如果是复杂类型，添加 schema
 			if isComplexType(field.Type) {
@@ -239,20 +316,75 @@ func generateParameters(requestType reflect.Type, handler *HandlerInfo) ([]Param
 	return parameters, definitions
 }
 
+func hasParameter(parameters []Parameter, name string) bool {
+	for _, parameter := range parameters {
+		if parameter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // 生成响应定义
 func generateResponses(handler *HandlerInfo) (map[string]Response, map[string]Definition) {
 	responseType := handler.Response
+	if elemType, ok := streamElemType(responseType); ok {
+		return generateStreamResponses(handler, elemType)
+	}
+	responseType = getBodyType(responseType)
+	status := handler.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	statusKey := strconv.Itoa(status)
+	var headers map[string]ResponseHeader
+	if status == http.StatusCreated || (status >= 300 && status < 400) {
+		headers = map[string]ResponseHeader{
+			"Location": {Type: "string"},
+		}
+	}
+	if !bodyAllowedForStatus(status) {
+		return map[string]Response{
+			statusKey: {
+				Description: http.StatusText(status),
+				Headers:     headers,
+			},
+		}, map[string]Definition{}
+	}
+
+	if isBinaryResponse(responseType) {
+		return map[string]Response{
+			"200": {
+				Description: "successful operation",
+				Schema:      Schema{Type: "file"},
+				Headers: map[string]ResponseHeader{
+					"Content-Disposition": {Type: "string"},
+				},
+			},
+		}, map[string]Definition{}
+	}
+	if isRedirectResponse(responseType) {
+		return map[string]Response{
+			"302": {
+				Description: "redirect",
+				Headers: map[string]ResponseHeader{
+					"Location": {Type: "string"},
+				},
+			},
+		}, map[string]Definition{}
+	}
 	if responseType.Kind() == reflect.Ptr {
 		responseType = responseType.Elem()
 	}
 
 	if !isComplexType(responseType) {
 		return map[string]Response{
-			"200": {
+			statusKey: {
 				Description: "successful operation",
 				Schema: Schema{
 					Type: getSwaggerType(responseType),
 				},
+				Headers: headers,
 			},
 		}, map[string]Definition{}
 	}
@@ -265,12 +397,13 @@ func generateResponses(handler *HandlerInfo) (map[string]Response, map[string]De
 	}
 
 	return map[string]Response{
-			"200": {
+			statusKey: {
 				Description: "successful operation",
 				Schema: Schema{
 					Type: "object",
 					Ref:  "#/definitions/" + definitionName,
 				},
+				Headers: headers,
 			},
 			"400": {
 				Description: "Invalid input",
@@ -283,6 +416,144 @@ func generateResponses(handler *HandlerInfo) (map[string]Response, map[string]De
 		}
 }
 
+// 将成功响应的 schema 替换为响应包装，流、文件和重定向等不经过 OnSuccess 的响应保持不变
+func applyEnvelope(handler *HandlerInfo, envelope *Envelope, responses map[string]Response) map[string]Definition {
+	if _, ok := streamElemType(handler.Response); ok {
+		return nil
+	}
+	if isBinaryResponse(handler.Response) || isRedirectResponse(handler.Response) {
+		return nil
+	}
+	status := handler.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	statusKey := strconv.Itoa(status)
+	response, ok := responses[statusKey]
+	if !ok || !bodyAllowedForStatus(status) {
+		return nil
+	}
+
+	definitionName := handler.ApiName + "Envelope"
+	if response.Schema.Ref != "" {
+		definitionName = strings.TrimPrefix(response.Schema.Ref, "#/definitions/") + "Envelope"
+	}
+	data := Property{
+		Type:  response.Schema.Type,
+		Ref:   response.Schema.Ref,
+		Items: response.Schema.Items,
+	}
+	response.Schema = Schema{
+		Type: "object",
+		Ref:  "#/definitions/" + definitionName,
+	}
+	responses[statusKey] = response
+	return map[string]Definition{
+		definitionName: envelope.Definition(data),
+	}
+}
+
+// 生成错误响应定义，处理器声明的错误按 HTTP 状态码分组
+func generateErrorResponses(handler *HandlerInfo, errorRenderer ErrorRenderer, registry *Registry) (map[string]Response, map[string]Definition) {
+	schema := Schema{
+		Type: "object",
+		Ref:  "#/definitions/" + errorDefinitionName,
+	}
+	messages := make(map[int][]string)
+	for _, err := range handler.Errors {
+		status := registry.HTTPStatus(err)
+		messages[status] = append(messages[status], fmt.Sprintf("%d: %s", err.GetCode(), err.GetMessage()))
+	}
+
+	responses := make(map[string]Response)
+	for status, message := range messages {
+		responses[strconv.Itoa(status)] = Response{
+			Description: strings.Join(message, "; "),
+			Schema:      schema,
+		}
+	}
+	responses["default"] = Response{
+		Description: "unexpected error",
+		Schema:      schema,
+	}
+	return responses, map[string]Definition{
+		errorDefinitionName: errorRenderer.Definition(),
+	}
+}
+
+// 生成 SSE 流的响应定义，schema 描述单个事件的 data
+func generateStreamResponses(handler *HandlerInfo, elemType reflect.Type) (map[string]Response, map[string]Definition) {
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	description := "event stream (" + sse.ContentType + ")"
+	if elemType == eventType || elemType.Kind() == reflect.Interface || !isComplexType(elemType) {
+		schemaType := "string"
+		if elemType != eventType && elemType.Kind() != reflect.Interface {
+			schemaType = getSwaggerType(elemType)
+		}
+		return map[string]Response{
+			"200": {
+				Description: description,
+				Schema:      Schema{Type: schemaType},
+			},
+		}, map[string]Definition{}
+	}
+
+	definitionName := elemType.Name()
+	if definitionName == "" {
+		definitionName = handler.ApiName + "Event"
+	}
+	return map[string]Response{
+		"200": {
+			Description: description,
+			Schema: Schema{
+				Type: "object",
+				Ref:  "#/definitions/" + definitionName,
+			},
+		},
+	}, map[string]Definition{
+		definitionName: generateDefinition(elemType),
+	}
+}
+
+// 生成 WebSocket 接口的操作，入站和出站消息分别以 Message 和 Reply 为后缀生成定义
+func generateWebSocketOperation(handler *HandlerInfo) (*Operation, map[string]Definition) {
+	definitions := make(map[string]Definition)
+	describe := func(t reflect.Type, suffix string) string {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || isInjectedType(reflect.PointerTo(t)) {
+			return ""
+		}
+		name := t.Name()
+		if name == "" {
+			name = handler.ApiName + suffix
+		}
+		definitions[name] = generateDefinition(t)
+		return "#/definitions/" + name
+	}
+
+	description := "WebSocket endpoint"
+	if ref := describe(handler.Request, "Message"); ref != "" {
+		description += ", inbound message: " + ref
+	}
+	response := Response{Description: "Switching Protocols"}
+	if ref := describe(handler.Response, "Reply"); ref != "" {
+		response.Schema = Schema{Type: "object", Ref: ref}
+	}
+
+	return &Operation{
+		Summary:     getSummaryFromHandler(handler),
+		Description: description,
+		Parameters:  []Parameter{},
+		Responses: map[string]Response{
+			"101": response,
+		},
+	}, definitions
+}
+
 // 生成定义
 func generateDefinition(requestType reflect.Type) Definition {
 	definition := Definition{
@@ -296,6 +567,9 @@ func generateDefinition(requestType reflect.Type) Definition {
 
 	for i := 0; i < requestType.NumField(); i++ {
 		field := requestType.Field(i)
+		if isInjectedType(field.Type) {
+			continue
+		}
 		fieldName, property := generateProperty(field)
 
 		// 处理嵌套结构体
@@ -339,6 +613,10 @@ func generateProperty(field reflect.StructField) (string, Property) {
 	if enum := field.Tag.Get("enum"); enum != "" {
 		property.Enum = strings.Split(enum, ",")
 	}
+	if IsSensitive(field) {
+		property.Format = "password"
+		property.WriteOnly = true
+	}
 
 	return fieldName, property
 }
@@ -370,6 +648,11 @@ func getSummaryFromHandler(handler interface{}) string {
 	return ""
 }
 
+// 判断是否是由框架注入、不属于接口文档的字段类型
+func isInjectedType(t reflect.Type) bool {
+	return t == reflect.TypeOf(&gin.Context{}) || t == connType
+}
+
 // 判断是否是复杂类型
 func isComplexType(t reflect.Type) bool {
 	return t.Kind() == reflect.Struct || t.Kind() == reflect.Slice
@@ -0,0 +1,113 @@
+package iz2go
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/LingHeChen/iz2go"
+
+// TracingConfig OpenTelemetry 链路追踪的配置
+type TracingConfig struct {
+	// TracerProvider 默认为 otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+	// Propagator 从请求头中提取上游的链路信息，默认为 W3C traceparent 和 baggage
+	Propagator propagation.TextMapPropagator
+}
+
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// UseTracing 为每个路由创建以方法和路由路径命名的 span，绑定参数、装饰器和 Execute 分别创建子 span，
+// 处理器可以通过 c.Request.Context() 获取当前 span。可以在 Build 之后调用
+func (e *Engine) UseTracing(config TracingConfig) {
+	provider := config.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	propagator := config.Propagator
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	e.tracing = &tracing{
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagator,
+	}
+}
+
+func (e *Engine) getTracing() *tracing {
+	if e == nil {
+		return nil
+	}
+	return e.tracing
+}
+
+// traceRoute 为路由创建根 span 后调用 next，没有启用链路追踪时直接调用 next
+func traceRoute(c *gin.Context, route *HandlerInfo, next gin.HandlerFunc) {
+	t := engineFromContext(c).getTracing()
+	if t == nil {
+		next(c)
+		return
+	}
+	ctx := t.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := t.tracer.Start(ctx, route.Method+" "+route.Path,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route.Path),
+			attribute.String("url.path", c.Request.URL.Path),
+		))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+
+	next(c)
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if err := ErrorFromContext(c); err != nil {
+		span.SetAttributes(attribute.Int("iz2go.error.code", err.GetCode()))
+		span.SetStatus(codes.Error, fmt.Sprintf("%d: %s", err.GetCode(), err.GetMessage()))
+	} else if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// startSpan 创建当前请求的子 span，并将请求的 context 替换为子 span 的 context，
+// 返回的函数结束子 span 并恢复原来的 context。没有启用链路追踪时什么也不做
+func startSpan(c *gin.Context, name string) func() {
+	t := engineFromContext(c).getTracing()
+	if t == nil {
+		return func() {}
+	}
+	parent := c.Request.Context()
+	ctx, span := t.tracer.Start(parent, name)
+	c.Request = c.Request.WithContext(ctx)
+	return func() {
+		span.End()
+		c.Request = c.Request.WithContext(parent)
+	}
+}
+
+// withSpan 在子 span 中调用 fn
+func withSpan[T any](c *gin.Context, name string, fn func() T) T {
+	defer startSpan(c, name)()
+	return fn()
+}
+
+// traceDecorator 为装饰器返回的处理函数创建子 span，嵌套的装饰器对应嵌套的 span
+func traceDecorator(name string, handlerFunc gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		end := startSpan(c, "decorator "+name)
+		defer end()
+		handlerFunc(c)
+	}
+}
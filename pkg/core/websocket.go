@@ -0,0 +1,292 @@
+package iz2go
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 嵌入到处理器中声明为 WebSocket 处理器，用法同 *iz2go.Get
+//
+// WebSocket 处理器的 Execute 参数为入站消息，返回值为出站消息，每收到一条消息调用一次：
+//
+//	func (api *Chat) Execute(msg struct {
+//		Conn *iz2go.Conn
+//		Text string `json:"text"`
+//	}) (*Reply, iz2go.IError)
+//
+// 返回 nil 指针时不发送回复，返回 IError 时发送 {"code","message"} 消息，连接保持打开
+type WebSocket struct{}
+
+func (w *WebSocket) GetMethod() string {
+	return "GET"
+}
+
+func (w *WebSocket) isWebSocket() {}
+
+type iWebSocket interface {
+	isWebSocket()
+}
+
+func IsWebSocket(handler interface{}) bool {
+	_, ok := handler.(iWebSocket)
+	return ok
+}
+
+// IWithConnect 连接建立后调用，返回错误时关闭连接
+type IWithConnect interface {
+	OnConnect(conn *Conn) IError
+}
+
+// IWithDisconnect 连接关闭后调用，err 为导致连接关闭的错误，正常关闭时为 nil
+type IWithDisconnect interface {
+	OnDisconnect(conn *Conn, err error)
+}
+
+// IWithWebSocketConfig 自定义 WebSocket 连接配置
+type IWithWebSocketConfig interface {
+	GetWebSocketConfig() WebSocketConfig
+}
+
+// Codec 消息编解码
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type WebSocketConfig struct {
+	// PingInterval 服务端发送 ping 的间隔，默认 30 秒，小于 0 时不发送
+	PingInterval time.Duration
+	// PongWait 等待 pong（或任意消息）的超时时间，默认 PingInterval 的两倍
+	PongWait time.Duration
+	// WriteWait 单条消息的写超时，默认 10 秒
+	WriteWait time.Duration
+	// ReadLimit 单条入站消息的最大字节数，0 表示不限制
+	ReadLimit int64
+	// CheckOrigin 校验 Origin，为空时只允许同源请求
+	CheckOrigin func(r *http.Request) bool
+	// Codec 消息编解码，默认 JSONCodec
+	Codec Codec
+}
+
+func ParseWebSocketConfig(handler interface{}) WebSocketConfig {
+	var config WebSocketConfig
+	if h, ok := handler.(IWithWebSocketConfig); ok {
+		config = h.GetWebSocketConfig()
+	}
+	if config.PingInterval == 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.PongWait <= 0 && config.PingInterval > 0 {
+		config.PongWait = 2 * config.PingInterval
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = 10 * time.Second
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+	return config
+}
+
+// Conn 一个 WebSocket 连接，Send 可以被多个 goroutine 并发调用
+type Conn struct {
+	*websocket.Conn
+	// Context 升级连接时的请求上下文，可以读取路径、查询参数和中间件设置的值
+	Context *gin.Context
+
+	config  WebSocketConfig
+	writeMu sync.Mutex
+}
+
+// Send 编码并发送一条消息
+func (c *Conn) Send(v interface{}) error {
+	data, err := c.config.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(websocket.TextMessage, data)
+}
+
+func (c *Conn) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+	return c.WriteMessage(messageType, data)
+}
+
+var connType = reflect.TypeOf(&Conn{})
+
+func webSocketHandlerFunc(handler interface{}, scope Scope, handlerFunc reflect.Value) gin.HandlerFunc {
+	instance := newInstanceProvider(handler, scope)
+	handlerName := handlerFunc.Type().In(0).String()
+	config := ParseWebSocketConfig(handler)
+	upgrader := websocket.Upgrader{CheckOrigin: config.CheckOrigin}
+
+	return func(c *gin.Context) {
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade 失败时已经写入了错误响应
+			return
+		}
+		conn := &Conn{Conn: ws, Context: c, config: config}
+		// 每个连接使用同一个处理器实例，请求级作用域的处理器每个连接一个实例
+		api := instance()
+		serveConn(conn, api, handlerFunc, handlerName)
+	}
+}
+
+func serveConn(conn *Conn, api reflect.Value, handlerFunc reflect.Value, handlerName string) {
+	handler := api.Interface()
+	config := conn.config
+
+	var closeErr error
+	defer func() {
+		conn.Close()
+		if h, ok := handler.(IWithDisconnect); ok {
+			h.OnDisconnect(conn, closeErr)
+		}
+	}()
+
+	if h, ok := handler.(IWithConnect); ok {
+		if err := h.OnConnect(conn); err != nil {
+			closeErr = err
+			conn.Send(gin.H{"code": err.GetCode(), "message": err.GetMessage()})
+			conn.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.GetMessage()))
+			return
+		}
+	}
+
+	if config.ReadLimit > 0 {
+		conn.SetReadLimit(config.ReadLimit)
+	}
+	if config.PingInterval > 0 {
+		conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		})
+		stop := make(chan struct{})
+		defer close(stop)
+		go keepAlive(conn, stop)
+	}
+
+	requestType := handlerFunc.Type().In(1)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				closeErr = err
+			}
+			return
+		}
+		if config.PingInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		}
+		if err := handleMessage(conn, api, handlerFunc, requestType, data, handlerName); err != nil {
+			closeErr = err
+			return
+		}
+	}
+}
+
+// handleMessage 解码一条入站消息并调用 Execute，只有写入失败时返回错误
+func handleMessage(conn *Conn, api reflect.Value, handlerFunc reflect.Value, requestType reflect.Type, data []byte, handlerName string) (writeErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			log.Printf("[iz2go] panic recovered: WS %s (%s): %v\n%s",
+				conn.Context.FullPath(), handlerName, r, err.Stack)
+			writeErr = conn.Send(gin.H{"code": err.GetCode(), "message": err.GetMessage()})
+		}
+	}()
+
+	request, err := decodeMessage(conn, requestType, data)
+	if err != nil {
+		return conn.Send(gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+	}
+	response, errValue := splitResult(handlerFunc.Call([]reflect.Value{api, request}))
+	if !errValue.IsNil() {
+		ierr := engineFromContext(conn.Context).getRegistry().ToIError(errValue.Interface().(error))
+		return conn.Send(gin.H{"code": ierr.GetCode(), "message": ierr.GetMessage()})
+	}
+	if response.Type() == noContentType {
+		return nil
+	}
+	switch response.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if response.IsNil() {
+			return nil
+		}
+	}
+	return conn.Send(response.Interface())
+}
+
+// decodeMessage 使用 Codec 解码消息，并注入 *Conn 和 *gin.Context 类型的字段
+func decodeMessage(conn *Conn, requestType reflect.Type, data []byte) (reflect.Value, error) {
+	switch requestType {
+	case connType:
+		return reflect.ValueOf(conn), nil
+	case reflect.TypeOf(conn.Context):
+		return reflect.ValueOf(conn.Context), nil
+	}
+
+	request := reflect.New(requestType)
+	if len(data) > 0 {
+		if err := conn.config.Codec.Unmarshal(data, request.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	request = request.Elem()
+	if requestType.Kind() == reflect.Struct {
+		for i := 0; i < requestType.NumField(); i++ {
+			field := request.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			switch requestType.Field(i).Type {
+			case connType:
+				field.Set(reflect.ValueOf(conn))
+			case reflect.TypeOf(conn.Context):
+				field.Set(reflect.ValueOf(conn.Context))
+			}
+		}
+	}
+	return request, nil
+}
+
+// keepAlive 定时发送 ping，写入失败时退出
+func keepAlive(conn *Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(conn.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.write(websocket.PingMessage, nil); err != nil {
+				if !errors.Is(err, websocket.ErrCloseSent) {
+					conn.Close()
+				}
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}